@@ -81,7 +81,8 @@ func printClock(writer *bufio.Writer, upper, lower int64, shift int) error {
 
 	// add one more digit to handle '-0'(RINEX2) or '-0000'(RINEX3)
 	// AT LEAST fractional parts are filled with 0
-	if lower < 0 {
+	// the sign of upper is also checked, or e.g., -0.100000000 would be printed as -.000000000
+	if lower < 0 || lower == 0 && upper < 0 {
 		line = fmt.Sprintf("%.*d", shift+1, upper*10-1)
 	} else {
 		line = fmt.Sprintf("%.*d", shift+1, upper*10+1)
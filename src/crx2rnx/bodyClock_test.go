@@ -0,0 +1,39 @@
+package crx2rnx
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+)
+
+func TestPrintClock(t *testing.T) {
+	for _, tc := range []struct {
+		upper, lower int64
+		shift        int
+		want         string
+	}{
+		{0, 123, 1, "  .000000123\n"},
+		{0, -12345, 1, " -.000012345\n"},
+		{1, 0, 1, "  .100000000\n"},
+		{-1, 0, 1, " -.100000000\n"}, // zero lower part
+		{-10, 0, 1, "-1.000000000\n"},
+		{-1, -12345678, 1, " -.112345678\n"},
+		{1, 0, 4, "  .000100000000\n"},
+		{-1, 0, 4, " -.000100000000\n"}, // zero lower part
+		{-10000, 0, 4, "-1.000000000000\n"},
+		{-10000, -12345678, 4, "-1.000012345678\n"},
+	} {
+		var buf bytes.Buffer
+		writer := bufio.NewWriter(&buf)
+
+		if err := printClock(writer, tc.upper, tc.lower, tc.shift); err != nil {
+			t.Fatalf("printClock(%d, %d, %d): %s", tc.upper, tc.lower, tc.shift, err)
+		}
+
+		writer.Flush()
+
+		if got := buf.String(); got != tc.want {
+			t.Errorf("printClock(%d, %d, %d) = %q, want %q", tc.upper, tc.lower, tc.shift, got, tc.want)
+		}
+	}
+}
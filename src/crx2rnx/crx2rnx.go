@@ -1,6 +1,7 @@
 /*
-A package used to recover a RINEX file from a Compact RINEX (CRINEX) file.
-It is compatable with CRX2RNX 4.2.0.
+A package used to recover a RINEX file from a Compact RINEX (CRINEX) file,
+and to compress a RINEX observation file into a CRINEX file.
+It is compatable with CRX2RNX 4.2.0 and RNX2CRX 4.2.0.
 
 Reference:
  1. Hatanaka, Y. (2008), A Compression Format and Tools for GNSS Observation Data,
@@ -13,6 +14,7 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 )
@@ -62,9 +64,22 @@ func CRX2RNX(inFile string, outFile *string) error {
 
 	defer fo.Close()
 
-	scanner := bufio.NewScanner(fi)
-	writer := bufio.NewWriter(fo)
-	defer writer.Flush()
+	return CRX2RNXStream(fi, fo)
+}
+
+/***********************************************/
+
+// The same as CRX2RNX(), but the data are read from r and written into w.
+func CRX2RNXStream(r io.Reader, w io.Writer) error {
+	return CRX2RNXStreamHeader(r, w, nil)
+}
+
+/***********************************************/
+
+// The same as CRX2RNXStream(), and the station metadata in the header are stored into hdr if it is not nil.
+func CRX2RNXStreamHeader(r io.Reader, w io.Writer, hdr *Header) error {
+	scanner := bufio.NewScanner(r)
+	writer := bufio.NewWriter(w)
 
 	// 3. read and write the header
 	var (
@@ -73,7 +88,7 @@ func CRX2RNX(inFile string, outFile *string) error {
 		TypeNumGNSS    map[byte]int = make(map[byte]int)
 	)
 
-	err = header(scanner, writer, &crxVer, &rnxVer, TypeNumGNSS, &nl)
+	err := header(scanner, writer, &crxVer, &rnxVer, TypeNumGNSS, &nl, hdr)
 
 	if err != nil {
 		return fmt.Errorf("failed to generate the header. %s", err)
@@ -86,7 +101,7 @@ func CRX2RNX(inFile string, outFile *string) error {
 		return fmt.Errorf("failed to generate the body, %s", err)
 	}
 
-	return nil
+	return writer.Flush()
 }
 
 /***********************************************/
@@ -10,7 +10,7 @@ import (
 /***** FUNCTION ********************************/
 
 func header(scanner *bufio.Scanner, writer *bufio.Writer,
-	crxVer, rnxVer *int, TypeNumGNSS map[byte]int, nl *int64) (err error) {
+	crxVer, rnxVer *int, TypeNumGNSS map[byte]int, nl *int64, hdr *Header) (err error) {
 	var line, kw string
 	var num int
 
@@ -33,6 +33,7 @@ func header(scanner *bufio.Scanner, writer *bufio.Writer,
 		}
 
 		kw = line[60:]
+		hdr.parse(line)
 
 		if kw == "CRINEX VERS   / TYPE" {
 			if line[0:3] != "1.0" && line[0:3] != "3.0" && line[0:3] != "3.1" {
@@ -0,0 +1,120 @@
+package crx2rnx
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+/***** CONSTANT ********************************/
+
+const (
+	_ARC_ORDER_DATA  = 3 // order of difference for observation records
+	_ARC_ORDER_CLOCK = 3 // order of difference for clock offset
+)
+
+/***** FUNCTION ********************************/
+
+func RNX2CRX(inFile string, outFile *string) error {
+	// 1. check the input (rnx) file and the output (crx) file
+	if len(inFile) == 0 {
+		return errors.New("the input file name is empty")
+	}
+
+	// if the output file is empty, then the default name will be used
+	if len(*outFile) == 0 {
+		name, ok := CRXFileName(inFile)
+
+		if !ok {
+			return errors.New("invalid extension of the input file name, which should be [.??o], [.??O], [.rnx] or [.RNX]")
+		}
+
+		*outFile = name
+	}
+
+	// 2. open the input file and the output file
+	fi, err := os.Open(inFile)
+
+	if err != nil {
+		return err
+	}
+
+	defer fi.Close()
+
+	fo, err := os.Create(*outFile)
+
+	if err != nil {
+		return err
+	}
+
+	defer fo.Close()
+
+	return RNX2CRXStream(fi, fo)
+}
+
+/***********************************************/
+
+// Get the name of the CRINEX file from the name of the RINEX observation file, i.e.,
+// [.rnx] to [.crx], [.RNX] to [.CRX], [.??o] to [.??d] and [.??O] to [.??D].
+// The second returned value is false if the extension is none of them.
+func CRXFileName(rnxFile string) (string, bool) {
+	idx := strings.LastIndexByte(rnxFile, '.')
+
+	if idx < 0 || len(rnxFile)-1-idx != 3 {
+		return rnxFile, false
+	}
+
+	if rnxFile[idx+1:] == "rnx" {
+		return rnxFile[:idx+1] + "crx", true
+	} else if rnxFile[idx+1:] == "RNX" {
+		return rnxFile[:idx+1] + "CRX", true
+	} else if rnxFile[idx+3] == 'o' {
+		return rnxFile[:idx+3] + "d", true
+	} else if rnxFile[idx+3] == 'O' {
+		return rnxFile[:idx+3] + "D", true
+	}
+
+	return rnxFile, false
+}
+
+/***********************************************/
+
+// The same as RNX2CRX(), but the data are read from r and written into w.
+func RNX2CRXStream(r io.Reader, w io.Writer) error {
+	return RNX2CRXStreamHeader(r, w, nil)
+}
+
+/***********************************************/
+
+// The same as RNX2CRXStream(), and the station metadata in the header are stored into hdr if it is not nil.
+func RNX2CRXStreamHeader(r io.Reader, w io.Writer, hdr *Header) error {
+	scanner := bufio.NewScanner(r)
+	writer := bufio.NewWriter(w)
+
+	// 3. read and write the header
+	var (
+		nl             int64
+		crxVer, rnxVer int
+		TypeNumGNSS    map[byte]int = make(map[byte]int)
+	)
+
+	err := encodeHeader(scanner, writer, &crxVer, &rnxVer, TypeNumGNSS, &nl, hdr)
+
+	if err != nil {
+		return fmt.Errorf("failed to generate the header. %s", err)
+	}
+
+	// 4. read and write the body
+	err = encodeBody(scanner, writer, crxVer, rnxVer, TypeNumGNSS, &nl)
+
+	if err != nil {
+		return fmt.Errorf("failed to generate the body, %s", err)
+	}
+
+	return writer.Flush()
+}
+
+/***********************************************/
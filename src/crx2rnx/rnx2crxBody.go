@@ -0,0 +1,460 @@
+package crx2rnx
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/***** METHOD **********************************/
+
+func (c *_ClockFormat) get(order int) int64 {
+	return c.upper[order]*100000000 + c.lower[order]
+}
+
+/***********************************************/
+
+func (c *_ClockFormat) set(order int, value int64) {
+	c.upper[order] = value / 100000000
+	c.lower[order] = value % 100000000
+}
+
+/***********************************************/
+
+func (d *_DataFormat) get(order int) int64 {
+	return d.upper[order]*100000 + d.lower[order]
+}
+
+/***********************************************/
+
+func (d *_DataFormat) set(order int, value int64) {
+	d.upper[order] = value / 100000
+	d.lower[order] = value % 100000
+}
+
+/***** FUNCTION ********************************/
+
+// Parse a fixed-point number, e.g., "  23619118.470", into an integer in units of the last decimal digit.
+// The second returned value is false if the field is blank.
+func parseFixed(field string, decimals int) (int64, bool, error) {
+	field = strings.TrimSpace(field)
+
+	if len(field) == 0 {
+		return 0, false, nil
+	}
+
+	intStr, decStr, _ := strings.Cut(field, ".")
+
+	if len(decStr) > decimals {
+		decStr = decStr[:decimals]
+	} else {
+		decStr += strings.Repeat("0", decimals-len(decStr))
+	}
+
+	neg := false
+
+	if strings.HasPrefix(intStr, "-") {
+		neg = true
+		intStr = intStr[1:]
+	}
+
+	value, err := strconv.ParseInt(strings.TrimSpace(intStr)+decStr, 10, 64)
+
+	if err != nil {
+		return 0, false, fmt.Errorf(`invalid field "%s"`, field)
+	}
+
+	if neg {
+		value = -value
+	}
+
+	return value, true, nil
+}
+
+/***********************************************/
+
+// The counterpart of repair(), the differences between the old line and the new line are written into ds.
+// The unchanged characters are replaced with ' ', and the characters changed into ' ' are replaced with '&'.
+func strdiff(old, new []byte, ds *[]byte) {
+	*ds = (*ds)[0:0]
+
+	for i, c := range new {
+		if i >= len(old) {
+			*ds = append(*ds, c)
+		} else if c == old[i] {
+			*ds = append(*ds, ' ')
+		} else if c == ' ' {
+			*ds = append(*ds, '&')
+		} else {
+			*ds = append(*ds, c)
+		}
+	}
+
+	*ds = bytes.TrimRight(*ds, " ")
+}
+
+/***********************************************/
+
+// Pad the line with ' ' to the given length.
+func pad(lineSb []byte, length int) []byte {
+	for len(lineSb) < length {
+		lineSb = append(lineSb, ' ')
+	}
+
+	return lineSb
+}
+
+/***********************************************/
+
+func encodeBody(scanner *bufio.Scanner, writer *bufio.Writer, crxVer, rnxVer int,
+	TypeNumGNSS map[byte]int, nl *int64) (err error) {
+	var (
+		crxEpochSym                                         byte
+		eventFlagIdx, satNumIdx, satListIdx, clkIdx, clkDec int
+		satPerLine, typePerLine                             int
+	)
+
+	if rnxVer == 2 { // RINEX 2
+		crxEpochSym = '&' // Symbols indicating beginning of an epoch in CRINEX file
+		eventFlagIdx = 28 // index of the event flag in the epoch line
+		satNumIdx = 29    // index of the satellite number in the epoch line
+		satListIdx = 32   // index of the satellite list in the epoch line
+		clkIdx = 68       // index of the clock offset in the epoch line
+		clkDec = 9        // number of decimals of the clock offset
+		satPerLine = 12   // number of satellites in one epoch line
+		typePerLine = 5   // number of observation types in one data line
+	} else {
+		crxEpochSym = '>'
+		eventFlagIdx = 31
+		satNumIdx = 32
+		satListIdx = 41
+		clkIdx = 41
+		clkDec = 12
+	}
+
+	var (
+		line        string
+		lineSb      []byte
+		lineSbAll   = make([]byte, 0, _MAX_LINE_LEN) // epoch line and satellite list of the current epoch
+		lineSbAll0  = make([]byte, 0, _MAX_LINE_LEN) // epoch line and satellite list of the previous epoch
+		diffSb      = make([]byte, 0, _MAX_LINE_LEN)
+		outSb       = make([]byte, 0, _MAX_LINE_LEN)
+		mustInit    = true
+		satNum      int
+		satList     = make([]_TypePRN, 0, _MAX_SAT_NUM)
+		satList0    = make([]_TypePRN, 0, _MAX_SAT_NUM)
+		satInfoList = make([]_SatInfo, 0, _MAX_SAT_NUM)
+		records     = make([][]byte, 0, _MAX_SAT_NUM) // [i][]byte, observation records of the i-th satellite
+		clkStr      string
+		clkOrder0   = -1 // < 0 means that the clock offset is blank
+		clkOrder    int
+		clk0, clk   _ClockFormat
+		clkValue    int64
+		value       int64
+		ok          bool
+		data0       = make([][]_DataFormat, 0, _MAX_SAT_NUM) // [i][j]_DataFormat, i for satellite, j for observation type
+		data        = make([][]_DataFormat, 0, _MAX_SAT_NUM) // [i][j]_DataFormat, i for satellite, j for observation type
+		dataFlag0   = make([][]byte, 0, _MAX_SAT_NUM)        // [i][j]byte, i for satellite, j for observation type
+		dataFlag    = make([][]byte, 0, _MAX_SAT_NUM)        // [i][j]byte, i for satellite, j for observation type
+	)
+
+outer:
+	for scanner.Scan() {
+		*nl++
+		lineSb = bytes.TrimRight(scanner.Bytes(), " \t")
+		line = string(lineSb)
+
+		// skip blank lines between epochs
+		if len(line) == 0 {
+			continue outer
+		}
+
+		if len(line) <= satNumIdx || (rnxVer >= 3 && line[0] != '>') {
+			return fmt.Errorf(`after reading line %d, "%s", error occured. invalid epoch line`, *nl, line)
+		}
+
+		satNum = 0
+		fmt.Sscanf(line[satNumIdx:], "%d", &satNum)
+
+		// event occurs, the epoch line and the following records are written as they are
+		if line[eventFlagIdx] != '0' && line[eventFlagIdx] != '1' {
+			lineSb[0] = crxEpochSym
+			writer.Write(lineSb)
+			writer.WriteByte('\n')
+
+			var iTmp int
+
+			for i := 0; i < satNum && scanner.Scan(); i++ {
+				*nl++
+				lineSb = bytes.TrimRight(scanner.Bytes(), " \t")
+				line = string(lineSb)
+				writer.Write(lineSb)
+				writer.WriteByte('\n')
+
+				if len(line) > 78 && line[60:] == "# / TYPES OF OBSERV" && line[5] != ' ' { // for RINEX2
+					fmt.Sscanf(line, "%d", &iTmp)
+
+					if iTmp <= 0 {
+						return fmt.Errorf(`after reading line %d, "%s", error occured. invalid value`, *nl, line)
+					}
+
+					TypeNumGNSS[0] = iTmp
+				} else if len(line) > 78 && line[60:79] == "SYS / # / OBS TYPES" && line[0] != ' ' { // for RINEX3
+					fmt.Sscanf(line[3:], "%d", &iTmp)
+
+					if iTmp <= 0 {
+						return fmt.Errorf(`after reading line %d, "%s", error occured. invalid value`, *nl, line)
+					}
+
+					TypeNumGNSS[line[0]] = iTmp
+				}
+			}
+
+			mustInit = true
+			continue outer
+		}
+
+		if satNum <= 0 {
+			return fmt.Errorf(`after reading line %d, "%s", error occured. invalid number of satellites`, *nl, line)
+		}
+
+		// get the epoch line and the clock offset
+		lineSbAll = pad(append(lineSbAll[0:0], lineSb[:min(len(lineSb), satListIdx)]...), satListIdx)
+		clkStr = ""
+
+		if len(line) > clkIdx {
+			clkStr = line[clkIdx:]
+		}
+
+		if len(records) < satNum {
+			records = append(records, make([][]byte, satNum-len(records))...)
+		}
+
+		// get the satellite list and the observation records
+		if rnxVer == 2 {
+			lineSbAll = append(lineSbAll, lineSb[min(len(lineSb), satListIdx):min(len(lineSb), clkIdx)]...)
+
+			for i := satPerLine; i < satNum; i += satPerLine {
+				if !scanner.Scan() {
+					return fmt.Errorf(`after reading line %d, "%s", the satellite list seems to be truncated`, *nl, line)
+				}
+
+				*nl++
+				lineSb = bytes.TrimRight(scanner.Bytes(), " \t")
+				line = string(lineSb)
+				lineSbAll = pad(lineSbAll, satListIdx+3*i)
+				lineSbAll = append(lineSbAll, lineSb[min(len(lineSb), satListIdx):min(len(lineSb), clkIdx)]...)
+			}
+
+			lineSbAll = pad(lineSbAll, satListIdx+3*satNum)
+
+			if err = setSatInfo(rnxVer, TypeNumGNSS, lineSbAll[satListIdx:], satNum, satList0, &satList, &satInfoList); err != nil {
+				return fmt.Errorf(`after reading line %d, "%s", %s`, *nl, line, err)
+			}
+
+			for i := 0; i < satNum; i++ {
+				records[i] = records[i][0:0]
+
+				for j := 0; j < satInfoList[i].TypeNum; j += typePerLine {
+					if !scanner.Scan() {
+						return fmt.Errorf(`after reading line %d, "%s", invalid data line`, *nl, line)
+					}
+
+					*nl++
+					lineSb = bytes.TrimRight(scanner.Bytes(), " \t")
+					line = string(lineSb)
+					records[i] = pad(append(records[i], lineSb[:min(len(lineSb), 16*typePerLine)]...), 16*(j+typePerLine))
+				}
+			}
+		} else {
+			for i := 0; i < satNum; i++ {
+				if !scanner.Scan() {
+					return fmt.Errorf(`after reading line %d, "%s", invalid data line`, *nl, line)
+				}
+
+				*nl++
+				lineSb = bytes.TrimRight(scanner.Bytes(), " \t")
+				line = string(lineSb)
+
+				if len(line) < 3 {
+					return fmt.Errorf(`after reading line %d, "%s", invalid data line`, *nl, line)
+				}
+
+				lineSbAll = append(lineSbAll, lineSb[:3]...)
+				records[i] = append(records[i][0:0], lineSb[3:]...)
+			}
+
+			if err = setSatInfo(rnxVer, TypeNumGNSS, lineSbAll[satListIdx:], satNum, satList0, &satList, &satInfoList); err != nil {
+				return fmt.Errorf(`after reading line %d, "%s", %s`, *nl, line, err)
+			}
+		}
+
+		// initialization of the differential operation for epoch and satellite list
+		if mustInit {
+			lineSbAll0 = lineSbAll0[0:0]
+
+			for i := 0; i < satNum; i++ {
+				satInfoList[i].OldIdx = -1
+			}
+		}
+
+		// print the epoch line and the satellite list
+		lineSbAll = pad(lineSbAll, len(lineSbAll0))
+
+		if mustInit {
+			outSb = append(outSb[0:0], lineSbAll...)
+			outSb[0] = crxEpochSym
+			writer.Write(bytes.TrimRight(outSb, " "))
+		} else {
+			strdiff(lineSbAll0, lineSbAll, &diffSb)
+			writer.Write(diffSb)
+		}
+
+		writer.WriteByte('\n')
+
+		// print the differenced clock offset
+		if clkValue, ok, err = parseFixed(clkStr, clkDec); err != nil {
+			return fmt.Errorf(`after reading line %d, "%s", invalid clock offset. %s`, *nl, line, err)
+		}
+
+		if !ok {
+			clkOrder = -1
+		} else if mustInit || clkOrder0 < 0 {
+			clkOrder = 0
+			clk.set(0, clkValue)
+			fmt.Fprintf(writer, "%d&%d", _ARC_ORDER_CLOCK, clkValue)
+		} else {
+			clkOrder = min(clkOrder0+1, _ARC_ORDER_CLOCK)
+			clk.set(0, clkValue)
+
+			for i := 1; i <= clkOrder; i++ {
+				clk.set(i, clk.get(i-1)-clk0.get(i-1))
+			}
+
+			fmt.Fprintf(writer, "%d", clk.get(clkOrder))
+		}
+
+		writer.WriteByte('\n')
+
+		// print the differenced observation data
+		if len(data) < satNum {
+			data = append(data, make([][]_DataFormat, satNum-len(data))...)
+		}
+
+		if len(dataFlag) < satNum {
+			dataFlag = append(dataFlag, make([][]byte, satNum-len(dataFlag))...)
+		}
+
+		for i := 0; i < satNum; i++ {
+			info := satInfoList[i]
+			records[i] = pad(records[i], 16*info.TypeNum)
+			dataFlag[i] = dataFlag[i][0:0]
+			outSb = outSb[0:0]
+
+			if len(data[i]) < info.TypeNum {
+				data[i] = make([]_DataFormat, info.TypeNum)
+			}
+
+			for j := 0; j < info.TypeNum; j++ {
+				field := records[i][16*j : 16*j+16]
+				d := &data[i][j]
+
+				if value, ok, err = parseFixed(string(field[:14]), 3); err != nil {
+					return fmt.Errorf(`after reading line %d, "%s", invalid observation data. %s`, *nl, line, err)
+				}
+
+				if j > 0 {
+					outSb = append(outSb, ' ')
+				}
+
+				if !ok {
+					d.order = -1
+					d.arcOrder = -1 // < 0 means that the field is blank
+
+					if crxVer == 1 { // CRINEX 1 assumes that flags are always blank if data field is blank
+						field[14], field[15] = ' ', ' '
+					}
+				} else if info.OldIdx < 0 || data0[info.OldIdx][j].arcOrder < 0 { // arc initialization
+					d.order = 0
+					d.arcOrder = _ARC_ORDER_DATA
+					d.set(0, value)
+					outSb = fmt.Appendf(outSb, "%d&%d", d.arcOrder, value)
+				} else {
+					d0 := &data0[info.OldIdx][j]
+					d.arcOrder = d0.arcOrder
+					d.order = min(d0.order+1, d.arcOrder)
+					d.set(0, value)
+
+					for k := 1; k <= d.order; k++ {
+						d.set(k, d.get(k-1)-d0.get(k-1))
+					}
+
+					outSb = fmt.Appendf(outSb, "%d", d.get(d.order))
+				}
+
+				dataFlag[i] = append(dataFlag[i], field[14], field[15])
+			}
+
+			// print the differenced data flags
+			if mustInit {
+				diffSb = bytes.TrimRight(append(diffSb[0:0], dataFlag[i]...), " ")
+			} else if info.OldIdx < 0 {
+				// the decoder may still find the satellite in the list of an earlier epoch,
+				// so blank flags are marked with '&' explicitly
+				diffSb = append(diffSb[0:0], dataFlag[i]...)
+
+				for k, c := range diffSb {
+					if c == ' ' {
+						diffSb[k] = '&'
+					}
+				}
+			} else {
+				strdiff(dataFlag0[info.OldIdx], dataFlag[i], &diffSb)
+			}
+
+			outSb = append(outSb, ' ')
+			outSb = append(outSb, diffSb...)
+			writer.Write(bytes.TrimRight(outSb, " "))
+			writer.WriteByte('\n')
+		}
+
+		// store the values
+		mustInit = false
+		lineSbAll0 = append(lineSbAll0[0:0], lineSbAll...)
+		clk0, clkOrder0 = clk, clkOrder
+
+		if len(satList0) < satNum {
+			if cap(satList0) >= satNum {
+				satList0 = satList0[:satNum]
+			} else {
+				satList0 = make([]_TypePRN, satNum)
+			}
+		}
+
+		satList0 = satList0[:satNum]
+
+		if len(data0) < satNum {
+			data0 = append(data0, make([][]_DataFormat, satNum-len(data0))...)
+		}
+
+		if len(dataFlag0) < satNum {
+			dataFlag0 = append(dataFlag0, make([][]byte, satNum-len(dataFlag0))...)
+		}
+
+		for i := 0; i < satNum; i++ {
+			satList0[i] = satList[i]
+			data0[i] = append(data0[i][0:0], data[i]...)
+			dataFlag0[i] = append(dataFlag0[i][0:0], dataFlag[i]...)
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return fmt.Errorf(`after reading line %d, "%s", error occured. %s`, *nl, line, err)
+	}
+
+	return nil
+}
+
+/***********************************************/
@@ -0,0 +1,97 @@
+package crx2rnx
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+/***** CONSTANT ********************************/
+
+const _RNX2CRX_PROG = "RNX2CRX ver.4.2.0"
+
+/***** FUNCTION ********************************/
+
+func encodeHeader(scanner *bufio.Scanner, writer *bufio.Writer,
+	crxVer, rnxVer *int, TypeNumGNSS map[byte]int, nl *int64, hdr *Header) (err error) {
+	var line, kw string
+	var num int
+
+	for {
+		if !scanner.Scan() {
+			err = scanner.Err()
+
+			if err == nil {
+				return errors.New(`no "END OF HEADER"`)
+			} else {
+				return fmt.Errorf(`after reading line %d, "%s", error occured. %s`, *nl, line, err)
+			}
+		}
+
+		*nl++
+		line = strings.TrimRight(scanner.Text(), " \t")
+
+		if len(line) <= 60 {
+			return fmt.Errorf(`after reading line %d, "%s", the file was truncated`, *nl, line)
+		}
+
+		kw = line[60:]
+		hdr.parse(line)
+
+		if *nl == 1 {
+			if kw == "CRINEX VERS   / TYPE" {
+				return errors.New("the input file is already in the CRINEX format")
+			} else if kw != "RINEX VERSION / TYPE" {
+				return errors.New(`the first line is not "RINEX VERSION / TYPE"`)
+			}
+
+			*rnxVer = int(line[5] - '0')
+
+			if *rnxVer != 2 && *rnxVer != 3 && *rnxVer != 4 {
+				return errors.New("unsupported RINEX version, only RINEX version 2.x, 3.x or 4.x could be dealt with")
+			}
+
+			if line[20] != 'O' {
+				return errors.New("invalid file type, only observation files could be dealt with")
+			}
+
+			if *rnxVer == 2 {
+				*crxVer = 1
+				fmt.Fprintf(writer, "%-20.20s%-40.40sCRINEX VERS   / TYPE\n", "1.0", "COMPACT RINEX FORMAT")
+			} else {
+				*crxVer = 3
+				fmt.Fprintf(writer, "%-20.20s%-40.40sCRINEX VERS   / TYPE\n", "3.0", "COMPACT RINEX FORMAT")
+			}
+
+			fmt.Fprintf(writer, "%-40.40s%-20.20sCRINEX PROG / DATE\n", _RNX2CRX_PROG, time.Now().UTC().Format("02-Jan-06 15:04"))
+		} else if kw == "# / TYPES OF OBSERV" && line[5] != ' ' { // for RINEX 2
+			fmt.Sscanf(line, "%d", &num)
+
+			if num <= 0 {
+				return fmt.Errorf(`after reading line %d, "%s", invalid number of obs types, "%d"`, *nl, line, num)
+			}
+
+			TypeNumGNSS[0] = num
+		} else if kw == "SYS / # / OBS TYPES" && line[0] != ' ' { // for RINEX 3, RINEX 4
+			fmt.Sscanf(line[3:], "%d", &num)
+
+			if num <= 0 {
+				return fmt.Errorf(`after reading line %d, "%s", error occured. invalid number of obs types`, *nl, line)
+			}
+
+			TypeNumGNSS[line[0]] = num
+		}
+
+		fmt.Fprintln(writer, line)
+
+		if kw == "END OF HEADER" {
+			break
+		}
+	}
+
+	return nil
+}
+
+/***********************************************/
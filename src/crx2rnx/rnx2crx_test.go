@@ -0,0 +1,140 @@
+package crx2rnx
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// The samples are written in the layout printed by CRX2RNX, e.g., fractions without the leading 0,
+// so that the decoded files are expected to be byte-identical to them.
+
+// RINEX 2 observations with an event flag, blank fields and a satellite list over two lines.
+// The clock offsets are absent, negative with a zero lower part, positive and negative.
+const _RNX2_SAMPLE = `     2.11           OBSERVATION DATA    M (MIXED)           RINEX VERSION / TYPE
+TEST                                                        MARKER NAME
+     4    C1    L1    L2    P2                              # / TYPES OF OBSERV
+                                                            END OF HEADER
+ 21  1  1  0  0  0.0000000  0  3G01G02R03
+  23619118.470   124121234.12317  96715223.456 6  23619120.123
+  21234567.890   111588888.111 8
+  20111222.333   107511111.222 5  83619999.001 5  20111224.567
+ 21  1  1  0  0 30.0000000  0  3G01G02R03                            -.100000000
+  23619218.471   124121759.700 7  96715633.111 6  23619220.124
+  21234467.800   111588362.500 8  86951956.222 7  21234469.900
+  20111122.301   107510577.123 5                  20111124.500
+ 21  1  1  0  1  0.0000000  0  2G01G02                                .000000123
+  23619318.472   124122285.277 7  96716042.766 6  23619320.125
+  21234367.710   111587836.889 8  86951546.000 7  21234369.811
+ 21  1  1  0  1 30.0000000  4  2
+ANTENNA SWAPPED                                             COMMENT
+NEW ANTENNA HEIGHT MEASURED                                 COMMENT
+ 21  1  1  0  2  0.0000000  0 13G01R03G05G06G07G08G09G10G11G12G13G14-1.000000000
+                                G15
+  20000000.000      -12345.6781                           .500
+  20001111.111      -12345.6781                          1.250
+  20002222.222      -12345.6781                          1.250
+  20003333.333      -12345.6781                          1.250
+  20004444.444      -12345.6781                          1.250
+  20005555.555      -12345.6781                          1.250
+  20006666.666      -12345.6781                          1.250
+  20007777.777      -12345.6781                          1.250
+  20008888.888      -12345.6781                          1.250
+  20009999.999      -12345.6781                          1.250
+  20011111.110      -12345.6781                          1.250
+  20012222.221      -12345.6781                          1.250
+  20013333.332      -12345.6781                          1.250
+ 21  1  1  0  2 30.0000000  0 13G01R03G05G06G07G08G09G10G11G12G13G14  .000012345
+                                G15
+  20000100.000      -12245.678                           1.500
+  20001211.111      -12245.678           -.250           1.500
+  20002322.222      -12245.678                           1.500
+  20003433.333      -12245.678                           1.500
+  20004544.444      -12245.678                           1.500
+  20005655.555      -12245.678                           1.500
+  20006766.666      -12245.678                           1.500
+  20007877.777      -12245.678                           1.500
+  20008988.888      -12245.678                           1.500
+  20010099.999      -12245.678                           1.500
+  20011211.110      -12245.678                           1.500
+  20012322.221      -12245.678                           1.500
+  20013433.332      -12245.678                           1.500
+`
+
+// RINEX 3 observations with an event flag redefining the observation types and blank fields.
+// The clock offsets are absent, positive, negative and negative with a zero lower part.
+const _RNX3_SAMPLE = `     3.04           OBSERVATION DATA    M                   RINEX VERSION / TYPE
+TEST                                                        MARKER NAME
+G    4 C1C L1C D1C S1C                                      SYS / # / OBS TYPES
+R    2 C1C L1C                                              SYS / # / OBS TYPES
+E    3 C1X L1X S1X                                          SYS / # / OBS TYPES
+                                                            END OF HEADER
+> 2021 01 01 00 00  0.0000000  0  3
+G01  23619118.470   124121234.12317     -1234.567          45.250
+R03  20111222.333
+E11  25111222.333   131966123.456 8               7
+> 2021 01 01 00 00 30.0000000  0  3        .000100000000
+G01  23619218.471   124121759.700 7     -1233.001          45.500
+R03  20111122.301   107510577.123 5
+E11  25111122.222   131965600.100 8        47.000 7
+> 2021 01 01 00 01  0.0000000  0  3       -.000100000000
+G01  23619318.472   124122285.277 7     -1231.500            .750
+R03                 107510043.000 5
+E11  25111022.111   131965076.744 8        47.250 7
+> 2021 01 01 00 01 30.0000000  3  2
+NEW SITE OCCUPATION                                         COMMENT
+G    2 C1C L1C                                              SYS / # / OBS TYPES
+> 2021 01 01 00 02  0.0000000  0  2      -1.000000000000
+G05  22000000.000   115610000.25016
+G01  23619418.473   124122810.900 7
+> 2021 01 01 00 02 30.0000000  0  2      -1.000012345678
+G01  23619518.474   124123336.500 7
+G05  21999900.000                 6
+> 2021 01 01 00 03  0.0000000  0  1
+G05  21999800.000   115608948.125
+`
+
+func TestRNX2CRXRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		sample string
+	}{
+		{"RINEX2", _RNX2_SAMPLE},
+		{"RINEX3", _RNX3_SAMPLE},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var crx, rnx bytes.Buffer
+
+			if err := RNX2CRXStream(strings.NewReader(tc.sample), &crx); err != nil {
+				t.Fatalf("RNX2CRXStream: %s", err)
+			}
+
+			if err := CRX2RNXStream(bytes.NewReader(crx.Bytes()), &rnx); err != nil {
+				t.Fatalf("CRX2RNXStream: %s\n%s", err, crx.String())
+			}
+
+			if got := rnx.String(); got != tc.sample {
+				t.Errorf("round trip mismatch\n--- crx ---\n%s--- got ---\n%s--- want ---\n%s", crx.String(), got, tc.sample)
+			}
+		})
+	}
+}
+
+func TestCRXFileName(t *testing.T) {
+	for _, tc := range []struct {
+		rnx, crx string
+		ok       bool
+	}{
+		{"/data/ABMF00GLP_R_20210010000_01D_30S_MO.rnx", "/data/ABMF00GLP_R_20210010000_01D_30S_MO.crx", true},
+		{"ABMF00GLP_R_20210010000_01D_30S_MO.RNX", "ABMF00GLP_R_20210010000_01D_30S_MO.CRX", true},
+		{"abmf0010.21o", "abmf0010.21d", true},
+		{"ABMF0010.21O", "ABMF0010.21D", true},
+		{"abmf0010.21d", "abmf0010.21d", false},
+		{"abmf0010.21o.gz", "abmf0010.21o.gz", false},
+		{"abmf", "abmf", false},
+	} {
+		if crx, ok := CRXFileName(tc.rnx); crx != tc.crx || ok != tc.ok {
+			t.Errorf("CRXFileName(%q) = %q, %v, want %q, %v", tc.rnx, crx, ok, tc.crx, tc.ok)
+		}
+	}
+}
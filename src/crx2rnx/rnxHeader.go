@@ -0,0 +1,81 @@
+package crx2rnx
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/***** STRUCT **********************************/
+
+// Station metadata in the header of a RINEX observation file, which is the same in a CRINEX file.
+type Header struct {
+	Marker    string     `json:"marker name"`
+	RcvSerial string     `json:"receiver serial number"`
+	RcvType   string     `json:"receiver type"`
+	RcvVers   string     `json:"receiver version"`
+	AntSerial string     `json:"antenna serial number"`
+	AntType   string     `json:"antenna type"` // including the radome in columns 17-20
+	XYZ       [3]float64 `json:"approx position xyz"`
+	AntHEN    [3]float64 `json:"antenna delta hen"`
+}
+
+/***** METHOD **********************************/
+
+// Get the metadata from a header line, the other lines are ignored.
+func (h *Header) parse(line string) {
+	if h == nil || len(line) <= 60 {
+		return
+	}
+
+	field := func(st, ed int) string {
+		return strings.TrimSpace(line[min(st, len(line)):min(ed, len(line))])
+	}
+
+	floats := func(v *[3]float64) {
+		for i := range v {
+			v[i], _ = strconv.ParseFloat(field(i*14, i*14+14), 64)
+		}
+	}
+
+	switch line[60:] {
+	case "MARKER NAME":
+		h.Marker = field(0, 60)
+	case "REC # / TYPE / VERS":
+		h.RcvSerial, h.RcvType, h.RcvVers = field(0, 20), field(20, 40), field(40, 60)
+	case "ANT # / TYPE":
+		h.AntSerial, h.AntType = field(0, 20), field(20, 40)
+	case "APPROX POSITION XYZ":
+		floats(&h.XYZ)
+	case "ANTENNA: DELTA H/E/N":
+		floats(&h.AntHEN)
+	}
+}
+
+/***** FUNCTION ********************************/
+
+// Read the metadata in the header of a RINEX or CRINEX observation file.
+func ReadHeader(r io.Reader) (*Header, error) {
+	var h Header
+
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t")
+		h.parse(line)
+
+		if len(line) > 60 && line[60:] == "END OF HEADER" {
+			return &h, nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, errors.New(`no "END OF HEADER"`)
+}
+
+/***********************************************/
@@ -124,6 +124,22 @@ func (d *Date) SubEq(days int32) {
 
 /***********************************************/
 
+// Add months to the date, and the day is clipped to the last day of the resulting month.
+func (d Date) AddMonth(months int32) Date {
+	year, month, day := ord2ymd(d.ord)
+	year, month, day = addMonth(year, month, day, months)
+	return Date{ymd2ord(year, month, day)}
+}
+
+/***********************************************/
+
+// Add years to the date, and 29-Feb is clipped to 28-Feb in common years.
+func (d Date) AddYear(years int32) Date {
+	return d.AddMonth(years * 12)
+}
+
+/***********************************************/
+
 func (d Date) SubDate(other Date) int32 {
 	return d.ord - other.ord
 }
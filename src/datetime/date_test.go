@@ -0,0 +1,85 @@
+package datetime
+
+import "testing"
+
+func TestAddMonth(t *testing.T) {
+	for _, tc := range []struct {
+		year       int32
+		month, day uint8
+		months     int32
+		wantYear   int32
+		wantMonth  uint8
+		wantDay    uint8
+	}{
+		{2021, 1, 15, 1, 2021, 2, 15},
+		{2021, 1, 31, 1, 2021, 2, 28}, // clipped to the end of February
+		{2024, 1, 31, 1, 2024, 2, 29}, // leap year
+		{2021, 3, 31, 1, 2021, 4, 30},
+		{2021, 11, 30, 3, 2022, 2, 28}, // crossing the year boundary
+		{2021, 12, 1, 1, 2022, 1, 1},
+		{2021, 12, 31, 25, 2024, 1, 31},
+		{2021, 3, 31, -1, 2021, 2, 28}, // negative offsets
+		{2022, 1, 15, -1, 2021, 12, 15},
+		{2022, 2, 28, -3, 2021, 11, 28},
+		{2022, 1, 31, -14, 2020, 11, 30},
+		{2021, 5, 31, 0, 2021, 5, 31},
+	} {
+		year, month, day := Date2Date(tc.year, tc.month, tc.day).AddMonth(tc.months).Date()
+
+		if year != tc.wantYear || month != tc.wantMonth || day != tc.wantDay {
+			t.Errorf("%04d-%02d-%02d AddMonth(%d) = %04d-%02d-%02d, want %04d-%02d-%02d",
+				tc.year, tc.month, tc.day, tc.months, year, month, day, tc.wantYear, tc.wantMonth, tc.wantDay)
+		}
+	}
+}
+
+/***********************************************/
+
+func TestAddYear(t *testing.T) {
+	for _, tc := range []struct {
+		year       int32
+		month, day uint8
+		years      int32
+		wantYear   int32
+		wantMonth  uint8
+		wantDay    uint8
+	}{
+		{2024, 2, 29, 1, 2025, 2, 28}, // clipped in the common year
+		{2024, 2, 29, 4, 2028, 2, 29},
+		{2024, 2, 29, -1, 2023, 2, 28},
+		{2021, 12, 31, 1, 2022, 12, 31},
+		{2021, 1, 1, -3, 2018, 1, 1},
+	} {
+		year, month, day := Date2Date(tc.year, tc.month, tc.day).AddYear(tc.years).Date()
+
+		if year != tc.wantYear || month != tc.wantMonth || day != tc.wantDay {
+			t.Errorf("%04d-%02d-%02d AddYear(%d) = %04d-%02d-%02d, want %04d-%02d-%02d",
+				tc.year, tc.month, tc.day, tc.years, year, month, day, tc.wantYear, tc.wantMonth, tc.wantDay)
+		}
+	}
+}
+
+/***********************************************/
+
+func TestTimeAddMonth(t *testing.T) {
+	for _, tc := range []struct {
+		t    Time
+		add  func(Time) Time
+		want Time
+	}{
+		{DateTime2Time(TIME_SYS_GPST, 2021, 1, 31, 12, 30, 15), func(t Time) Time { return t.AddMonth(1) },
+			DateTime2Time(TIME_SYS_GPST, 2021, 2, 28, 12, 30, 15)},
+		{DateTime2Time(TIME_SYS_GPST, 2021, 12, 31, 23, 59, 30), func(t Time) Time { return t.AddMonth(2) },
+			DateTime2Time(TIME_SYS_GPST, 2022, 2, 28, 23, 59, 30)},
+		{DateTime2Time(TIME_SYS_GPST, 2022, 1, 15, 6, 0, 0), func(t Time) Time { return t.AddMonth(-1) },
+			DateTime2Time(TIME_SYS_GPST, 2021, 12, 15, 6, 0, 0)},
+		{DateTime2Time(TIME_SYS_GPST, 2024, 2, 29, 0, 0, 0), func(t Time) Time { return t.AddYear(1) },
+			DateTime2Time(TIME_SYS_GPST, 2025, 2, 28, 0, 0, 0)},
+	} {
+		if got := tc.add(tc.t); got.Format("{D} {T}") != tc.want.Format("{D} {T}") {
+			t.Errorf("%s: got %s, want %s", tc.t.Format("{D} {T}"), got.Format("{D} {T}"), tc.want.Format("{D} {T}"))
+		}
+	}
+}
+
+/***********************************************/
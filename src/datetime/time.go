@@ -385,6 +385,24 @@ func (t Time) Sub(other Time) Time {
 
 /***********************************************/
 
+// Add months to the time, and the day is clipped to the last day of the resulting month.
+// The time of day is kept unchanged.
+func (t Time) AddMonth(months int32) Time {
+	year, month, day, hour, minute, second := t.DateTime()
+	year, month, day = addMonth(year, month, day, months)
+	return DateTime2Time(t.sys, year, month, day, hour, minute, second)
+}
+
+/***********************************************/
+
+// Add years to the time, and 29-Feb is clipped to 28-Feb in common years.
+// The time of day is kept unchanged.
+func (t Time) AddYear(years int32) Time {
+	return t.AddMonth(years * 12)
+}
+
+/***********************************************/
+
 func (t Time) Mul(c float64) Time {
 	t.MulEq(c)
 	return t
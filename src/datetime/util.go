@@ -13,6 +13,35 @@ func isLeapYear(year int32) bool {
 
 /***********************************************/
 
+// Get the number of days in the month.
+func daysInMonth(year int32, month uint8) uint8 {
+	if isLeapYear(year) && month == 2 {
+		return 29
+	}
+
+	return _DAYS_IN_MONTH[month-1]
+}
+
+/***********************************************/
+
+// Add months to year/month/day, and the day is clipped to the last day of the resulting month.
+func addMonth(year int32, month, day uint8, months int32) (int32, uint8, uint8) {
+	idx := year*12 + int32(month) - 1 + months
+	year = idx / 12
+	idx %= 12
+
+	if idx < 0 {
+		year--
+		idx += 12
+	}
+
+	month = uint8(idx) + 1
+	day = min(day, daysInMonth(year, month))
+	return year, month, day
+}
+
+/***********************************************/
+
 // Convert year/month/day to ordinal.
 func ymd2ord(year int32, month, day uint8) int32 {
 	if month < 1 || month > 12 {
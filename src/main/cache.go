@@ -0,0 +1,66 @@
+package main
+
+import "sync"
+
+/***** STRUCT **********************************/
+
+// A cache of values fetched once per key, e.g., manifests or listings of remote directories.
+// The failed fetching is not cached, so that it is tried again by the next caller.
+type tCache[T any] struct {
+	mutex sync.Mutex
+	items map[string]*tCacheItem[T]
+}
+
+/***********************************************/
+
+type tCacheItem[T any] struct {
+	done chan struct{} // closed when the value is fetched
+	val  T
+	err  error
+}
+
+/***** METHOD **********************************/
+
+func (c *tCache[T]) Get(key string, fetch func() (T, error)) (T, error) {
+	c.mutex.Lock()
+
+	if c.items == nil {
+		c.items = make(map[string]*tCacheItem[T])
+	}
+
+	item, ok := c.items[key]
+
+	if !ok {
+		item = &tCacheItem[T]{done: make(chan struct{})}
+		c.items[key] = item
+	}
+
+	c.mutex.Unlock()
+
+	if !ok {
+		item.val, item.err = fetch()
+
+		if item.err != nil {
+			c.mutex.Lock()
+			delete(c.items, key)
+			c.mutex.Unlock()
+		}
+
+		close(item.done)
+	}
+
+	<-item.done
+	return item.val, item.err
+}
+
+/***********************************************/
+
+// Drop all the values, so that they are fetched again, e.g., in the next round of the daemon.
+func (c *tCache[T]) Reset() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.items = nil
+}
+
+/***********************************************/
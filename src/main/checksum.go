@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"godog/network"
+	"hash"
+	"io"
+	"log"
+	"net/url"
+	"path"
+	"strings"
+)
+
+/***** VARIABLE ********************************/
+
+var manifestCache tCache[map[string]string] // key: url of the manifest
+
+/***** FUNCTION ********************************/
+
+// Parse a manifest in the format of "md5sum"/"sha512sum", i.e., "<checksum>  <name>",
+// or in the BSD format, i.e., "SHA512 (<name>) = <checksum>".
+func parseManifest(r io.Reader) map[string]string {
+	var (
+		sums    = make(map[string]string)
+		scanner = bufio.NewScanner(r)
+	)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+
+		if idx := strings.Index(line, ") = "); idx > 0 && strings.Contains(line[:idx], " (") {
+			name := line[strings.Index(line, " (")+2 : idx]
+			sums[path.Base(name)] = strings.ToLower(strings.TrimSpace(line[idx+4:]))
+		} else if fields := strings.Fields(line); len(fields) >= 2 {
+			name := strings.TrimPrefix(strings.Join(fields[1:], " "), "*")
+			sums[path.Base(name)] = strings.ToLower(fields[0])
+		}
+	}
+
+	return sums
+}
+
+/***********************************************/
+
+// Get the hash function according to the length of the checksum in hex.
+func newHash(sum string) (hash.Hash, error) {
+	switch len(sum) {
+	case 2 * md5.Size:
+		return md5.New(), nil
+	case 2 * sha1.Size:
+		return sha1.New(), nil
+	case 2 * sha256.Size:
+		return sha256.New(), nil
+	case 2 * sha512.Size:
+		return sha512.New(), nil
+	default:
+		return nil, fmt.Errorf(`unknown type of checksum "%s"`, sum)
+	}
+}
+
+/***********************************************/
+
+// Get the url of the manifest, a template without scheme is relative to the directory of the source.
+func getManifestURL(job *Job, source network.NetworkInfo, template string) (string, error) {
+	rawURL := getPathURL(job.Time, job.Name, template)
+
+	if strings.Contains(rawURL, "://") {
+		return rawURL, nil
+	}
+
+	pURL, err := url.Parse(source.Url)
+
+	if err != nil {
+		return "", err
+	}
+
+	pURL.Path = path.Join(path.Dir(pURL.Path), rawURL)
+	pURL.RawPath = ""
+	return pURL.String(), nil
+}
+
+/***********************************************/
+
+// Get the checksum of the file in the source from the manifest, which is fetched once per url.
+// An empty string is returned if the manifest does not exist or the file is not listed, and the file is not verified.
+func getChecksum(job *Job, source network.NetworkInfo) (string, error) {
+	var err error
+	source.Url, err = getManifestURL(job, source, rsMap[job.Product].Checksum)
+
+	if err != nil {
+		return "", network.NewTaskError(fmt.Errorf("invalid url of the manifest, %s", err), false)
+	}
+
+	sums, err := manifestCache.Get(source.Url, func() (map[string]string, error) {
+		buf := new(bytes.Buffer)
+		netTask := network.NetworkTask{Source: source, Writer: buf}
+
+		if tErr := download(&netTask); network.IsNotFound(tErr) {
+			log.Printf("[info] manifest %s is not found, the files listed in it are not verified", source.Url)
+		} else if tErr != nil {
+			return nil, network.NewTaskError(fmt.Errorf("failed to fetch the manifest %s, %s", source.Url, tErr), true)
+		}
+
+		return parseManifest(buf), nil
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	sum, ok := sums[path.Base(job.Url)]
+
+	if !ok && len(sums) != 0 {
+		log.Printf("[info] %s is not listed in the manifest %s, which is not verified", path.Base(job.Url), source.Url)
+	}
+
+	return sum, nil
+}
+
+/***********************************************/
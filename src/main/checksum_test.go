@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"encoding/hex"
+	"godog/network"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFetchChecksum(t *testing.T) {
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write([]byte("fake file content\n"))
+	w.Close()
+
+	good := gz.Bytes()
+	bad := append([]byte(nil), good...)
+	bad[len(bad)/2] ^= 0xff // corrupted in transit
+
+	sum := md5.Sum(good)
+
+	for _, tc := range []struct {
+		name    string
+		payload []byte
+		wantErr string
+	}{
+		{"verified", good, ""},
+		{"corrupted", bad, "checksum mismatch"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write(tc.payload)
+			}))
+			defer server.Close()
+
+			desFile := filepath.Join(t.TempDir(), "file.txt")
+			job := Job{Unzip: true}
+			netTask := network.NetworkTask{Source: network.NetworkInfo{Url: server.URL + "/file.txt.gz"}}
+			err := fetch(&job, &netTask, desFile, hex.EncodeToString(sum[:]))
+
+			if len(tc.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("fetch: %s", err)
+				}
+
+				if got, _ := os.ReadFile(desFile); string(got) != "fake file content\n" {
+					t.Errorf("got %q, want the decompressed content", got)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("fetch: %v, want %q", err, tc.wantErr)
+			}
+
+			if _, err := os.Stat(desFile + ".raw"); !os.IsNotExist(err) {
+				t.Errorf("the raw file is left, %v", err)
+			}
+		})
+	}
+}
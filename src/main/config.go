@@ -5,9 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"godog/datetime"
+	"godog/network"
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 )
 
 /***** CONSTANT ********************************/
@@ -17,39 +23,64 @@ const (
 	MAX_GOROUTINE_NUM = 999
 	MIN_RETRY_NUM     = 1
 	MAX_RETRY_NUM     = 99
+	MIN_SESSION_NUM   = 1
+	MAX_SESSION_NUM   = 99
 )
 
 /***** STRUCT **********************************/
 
 type Task struct {
-	Type     string   `json:"type"`
-	Path     string   `json:"path"`
-	Backward int      `json:"backward"`
-	Forward  int      `json:"forward"`
-	IfUnzip  bool     `json:"decompress"`
-	IfForce  bool     `json:"force"`
-	InfoFile string   `json:"information"`
-	Targets  []string `json:"targets"`
+	Type      string          `json:"type"`
+	Path      string          `json:"path"`
+	Backward  int             `json:"backward"`
+	Forward   int             `json:"forward"`
+	IfUnzip   bool            `json:"decompress"`
+	IfForce   bool            `json:"force"`
+	IfCompact bool            `json:"compact"`  // store observation files as CRINEX, named with [.crx] or [.??d]
+	IfSync    bool            `json:"sync"`     // download the existing file again if the remote one is newer or of a different size
+	Schedule  any             `json:"schedule"` // period of doing the task in the daemon mode, e.g., "1 hour"
+	Period    time.Duration   `json:"-"`
+	InfoFile  string          `json:"information"`
+	MetaPath  string          `json:"metadata"`  // path of the metadata file of each target, e.g., "/data/{04y}/{03O}/{+9.9R}.json"
+	LogFile   string          `json:"site logs"` // json file of the site logs by todog, for the equipment at the epoch in "metadata"
+	Targets   []string        `json:"targets"`
+	Fallback  []string        `json:"fallback"` // types tried in order if the file of "type" is not available
+	Select    *TargetSelector `json:"select"`   // selectors of targets from the information file, added to "targets"
+}
+
+/***********************************************/
+
+// Limits of requests to one host, zero or empty means unlimited.
+type tHostLimit struct {
+	MaxConn   int     `json:"max connections"`
+	Rate      float64 `json:"requests per second"`
+	Bandwidth string  `json:"bandwidth"` // e.g., "500 KB/s"
 }
 
 /***********************************************/
 
 type tConfig struct {
-	StTime   string `json:"start time"`
-	EdTime   string `json:"end time"`
-	GoNum    int    `json:"goroutine num"`
-	RetryNum int    `json:"retry num"`
-	Tasks    []Task `json:"tasks"`
+	StTime     string                `json:"start time"`
+	EdTime     string                `json:"end time"`
+	GoNum      int                   `json:"goroutine num"`
+	RetryNum   int                   `json:"retry num"`
+	SessionNum int                   `json:"ftp session num"` // maximum number of FTP/FTPS sessions per host, optional
+	Hosts      map[string]tHostLimit `json:"hosts"`           // key: host name, "*" for all other hosts, optional
+	Tasks      []Task                `json:"tasks"`
 }
 
 /***********************************************/
 
 type Config struct {
-	StTime   datetime.Time
-	EdTime   datetime.Time
-	GoNum    int
-	RetryNum int
-	Tasks    []Task
+	StTime     datetime.Time
+	EdTime     datetime.Time
+	StExpr     string // expression of the start time, which may be relative to now, e.g., "now-3d"
+	EdExpr     string // expression of the end time
+	GoNum      int
+	RetryNum   int
+	SessionNum int
+	Hosts      map[string]network.HostLimit
+	Tasks      []Task
 }
 
 /***** FUNCTION ********************************/
@@ -88,8 +119,15 @@ func (cfg *Config) ParseJson(cfgFile string) error {
 	}
 
 	// check the arc
-	cfg.StTime = datetime.Str2Time(tCfg.StTime)
-	cfg.EdTime = datetime.Str2Time(tCfg.EdTime)
+	if cfg.StTime, err = ParseTimeExpr(tCfg.StTime); err != nil {
+		return fmt.Errorf(`invalid "start time", %s`, err)
+	}
+
+	if cfg.EdTime, err = ParseTimeExpr(tCfg.EdTime); err != nil {
+		return fmt.Errorf(`invalid "end time", %s`, err)
+	}
+
+	cfg.StExpr, cfg.EdExpr = tCfg.StTime, tCfg.EdTime
 
 	if cfg.EdTime.Lt(cfg.StTime) {
 		return errors.New("invalid arc")
@@ -109,12 +147,42 @@ func (cfg *Config) ParseJson(cfgFile string) error {
 
 	cfg.RetryNum = tCfg.RetryNum
 
+	// check the FTP/FTPS session num
+	if tCfg.SessionNum == 0 {
+		tCfg.SessionNum = network.DefaultSessionNum
+	} else if tCfg.SessionNum < MIN_SESSION_NUM || tCfg.SessionNum > MAX_SESSION_NUM {
+		return fmt.Errorf(`value in "ftp session num" must be in %d-%d`, MIN_SESSION_NUM, MAX_SESSION_NUM)
+	}
+
+	cfg.SessionNum = tCfg.SessionNum
+
+	// check the limits of hosts
+	cfg.Hosts = make(map[string]network.HostLimit, len(tCfg.Hosts))
+
+	for host, val := range tCfg.Hosts {
+		var limit network.HostLimit
+
+		if val.MaxConn < 0 {
+			return fmt.Errorf(`invalid "max connections" of host "%s" in "hosts"`, host)
+		} else if val.Rate < 0 {
+			return fmt.Errorf(`invalid "requests per second" of host "%s" in "hosts"`, host)
+		}
+
+		limit.MaxConn, limit.Rate = val.MaxConn, val.Rate
+
+		if len(val.Bandwidth) != 0 {
+			if limit.Bandwidth, err = ParseBandwidth(val.Bandwidth); err != nil {
+				return fmt.Errorf(`invalid "bandwidth" of host "%s" in "hosts", %s`, host, err)
+			}
+		}
+
+		cfg.Hosts[host] = limit
+	}
+
 	// check tasks, and get the total number of jobs
 	var (
-		numTaskMap    = make(map[string]int)
-		ts, te, dt, t datetime.Time
-		ordInt        int32
-		ordDec        float64
+		numTaskMap = make(map[string]int)
+		ts, te, t  datetime.Time
 	)
 
 	cfg.Tasks = make([]Task, 0, len(tCfg.Tasks))
@@ -124,6 +192,12 @@ func (cfg *Config) ParseJson(cfgFile string) error {
 			return fmt.Errorf(`invalid "type" of the %d-th task specified in "tasks"`, idx+1)
 		}
 
+		for _, typ := range task.Fallback {
+			if _, ok := rsMap[typ]; !ok || typ == task.Type {
+				return fmt.Errorf(`invalid type "%s" in "fallback" of the %d-th task specified in "tasks"`, typ, idx+1)
+			}
+		}
+
 		task.Path = filepath.ToSlash(task.Path)
 
 		if task.Backward < 0 {
@@ -134,6 +208,28 @@ func (cfg *Config) ParseJson(cfgFile string) error {
 			return fmt.Errorf(`invalid "forward" of the %d-th task specified in "tasks"`, idx+1)
 		}
 
+		if task.Select != nil && task.InfoFile == "" {
+			return fmt.Errorf(`"select" of the %d-th task requires "information"`, idx+1)
+		}
+
+		if task.MetaPath != "" && task.InfoFile == "" {
+			return fmt.Errorf(`"metadata" of the %d-th task requires "information"`, idx+1)
+		}
+
+		if task.LogFile != "" && task.MetaPath == "" {
+			return fmt.Errorf(`"site logs" of the %d-th task requires "metadata"`, idx+1)
+		}
+
+		task.MetaPath = filepath.ToSlash(task.MetaPath)
+
+		if task.LogFile != "" {
+			siteLogMap[task.Type] = new(SiteLogArray)
+
+			if err = siteLogMap[task.Type].parseJson(task.LogFile); err != nil {
+				return fmt.Errorf(`failed to parse the json file specified in "site logs" for the %d-th task, %s`, idx+1, err)
+			}
+		}
+
 		if task.InfoFile != "" {
 			targetInfoMap[task.Type] = new(TargetInfoArray)
 			err = targetInfoMap[task.Type].parseJson(task.InfoFile)
@@ -142,24 +238,48 @@ func (cfg *Config) ParseJson(cfgFile string) error {
 				return fmt.Errorf(`failed to parse the information file (json) specified in "information" for the %d-th task`, idx+1)
 			}
 
+			if task.Select != nil {
+				if err = task.Select.Check(); err != nil {
+					return fmt.Errorf(`invalid "select" of the %d-th task specified in "tasks", %s`, idx+1, err)
+				}
+
+				selected := targetInfoMap[task.Type].Select(task.Select)
+
+				if len(selected) == 0 {
+					return fmt.Errorf(`no target selected by "select" of the %d-th task specified in "tasks"`, idx+1)
+				}
+
+				log.Printf("[info] %d targets selected for the %d-th task", len(selected), idx+1)
+
+				for _, target := range selected {
+					if !slices.Contains(task.Targets, target) {
+						task.Targets = append(task.Targets, target)
+					}
+				}
+			}
+
 			if len(task.Targets) == 0 {
 				for _, target := range targetInfoMap[task.Type].Array {
 					task.Targets = append(task.Targets, target.Name)
 				}
-			} else {
-				targets := make([]string, 0, len(task.Targets))
-
-				for i, target := range task.Targets {
-					if j := targetInfoMap[task.Type].Index(target); j >= 0 {
-						targets = append(targets, targetInfoMap[task.Type].Array[j].Name)
-					} else {
-						log.Printf(`[error] the %d-th target "%s" for the %d-th task is not found in its information file, which would be ignored`, i+1, target, idx+1)
-						continue
-					}
-				}
+			} else if err = checkTargets(&task, idx); err != nil {
+				return err
+			}
+		}
 
-				task.Targets = targets
+		// the task is done every interval of the resource in the daemon mode by default, and at most every day
+		if task.Schedule != nil {
+			iv, err := ParseInterval(task.Schedule)
+
+			if err != nil || iv.Unit != INTERVAL_SECOND {
+				return fmt.Errorf(`invalid "schedule" of the %d-th task specified in "tasks", which must be in seconds, minutes, hours or days`, idx+1)
 			}
+
+			task.Period = time.Duration(iv.Num) * time.Second
+		} else if iv := rsMap[task.Type].Interval; iv.Unit == INTERVAL_SECOND {
+			task.Period = min(time.Duration(iv.Num)*time.Second, 24*time.Hour)
+		} else {
+			task.Period = 24 * time.Hour
 		}
 
 		numTaskMap[task.Type] += 1
@@ -170,22 +290,9 @@ func (cfg *Config) ParseJson(cfgFile string) error {
 
 		cfg.Tasks = append(cfg.Tasks, task)
 
-		ts = cfg.StTime.Sub(datetime.Seconds2Time(float64(task.Backward)))
-		te = cfg.EdTime.Add(datetime.Seconds2Time(float64(task.Forward)))
-		ts.ConvertSelf(rsMap[task.Type].TimeSys)
-		te.ConvertSelf(rsMap[task.Type].TimeSys)
-		dt = datetime.Seconds2Time(float64(rsMap[task.Type].Interval))
-		ordDec = float64(int32(ts.OrdTotal()/dt.OrdTotal())) * dt.OrdTotal()
-		ordInt = int32(ordDec)
-		ordDec -= float64(ordInt)
-
-		if -datetime.TIME_EPSILON < ordDec && ordDec < datetime.TIME_EPSILON {
-			ordDec = 0
-		}
+		ts, te = task.Arc(cfg.StTime, cfg.EdTime)
 
-		ts = datetime.Ord2Time(ts.Sys(), ordInt, ordDec)
-
-		for t = ts; t.Le(te); t.AddEq(dt) {
+		for t = ts; t.Le(te); t = rsMap[task.Type].Interval.Next(t) {
 			if len(task.Targets) != 0 {
 				jobNum += len(task.Targets)
 			} else {
@@ -198,3 +305,134 @@ func (cfg *Config) ParseJson(cfgFile string) error {
 }
 
 /***********************************************/
+
+// Resolve the targets of the task by the names in its information file, in which the names not found are ignored,
+// and the invalid or ambiguous ones are errors.
+func checkTargets(task *Task, idx int) error {
+	targets, notFound, err := targetInfoMap[task.Type].Resolve(task.Targets)
+
+	if err != nil {
+		return fmt.Errorf(`invalid "targets" of the %d-th task specified in "tasks", %s`, idx+1, err)
+	}
+
+	for _, target := range notFound {
+		log.Printf(`[error] the target "%s" for the %d-th task is not found in its information file, which would be ignored`, target, idx+1)
+	}
+
+	task.Targets = targets
+	return nil
+}
+
+/***********************************************/
+
+// Parse the time in the form of "GPST 2025 12 1 0 0 0", or relative to now, e.g., "now-3d", "GPST now-6h" or "now-1d+6h".
+func ParseTimeExpr(str string) (t datetime.Time, err error) {
+	subs := strings.Fields(str)
+	sys := datetime.TIME_SYS_GPST
+
+	if len(subs) != 0 {
+		if s, ok := datetime.Name2TimeSys[strings.ToUpper(subs[0])]; ok {
+			sys, subs = s, subs[1:]
+		}
+	}
+
+	expr := strings.ToLower(strings.Join(subs, ""))
+
+	// absolute time
+	if !strings.HasPrefix(expr, "now") {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf(`"%s", %v`, str, r)
+			}
+		}()
+
+		return datetime.Str2Time(str), nil
+	}
+
+	// relative time, the offsets are like "-3d", "+6h" or "-30min"
+	var seconds int
+	offsetExp := regexp.MustCompile(`^([+-])(\d+)([a-z]+)`)
+
+	for expr = expr[3:]; len(expr) != 0; {
+		matched := offsetExp.FindStringSubmatch(expr)
+
+		if matched == nil {
+			return t, fmt.Errorf(`invalid offset "%s" in "%s"`, expr, str)
+		}
+
+		num, _ := strconv.Atoi(matched[2])
+		unit, ok := name2Seconds[matched[3]]
+
+		if !ok {
+			return t, fmt.Errorf(`invalid unit "%s" in "%s"`, matched[3], str)
+		}
+
+		if matched[1] == "-" {
+			num = -num
+		}
+
+		seconds += num * unit
+		expr = expr[len(matched[0]):]
+	}
+
+	return datetime.Now2Time(sys).Add(datetime.Seconds2Time(float64(seconds))), nil
+}
+
+/***********************************************/
+
+// Get the arc of the config, which moves with now if the start or end time is relative.
+func (cfg *Config) Window() (stTime, edTime datetime.Time) {
+	var err error
+
+	if stTime, err = ParseTimeExpr(cfg.StExpr); err != nil {
+		stTime = cfg.StTime
+	}
+
+	if edTime, err = ParseTimeExpr(cfg.EdExpr); err != nil {
+		edTime = cfg.EdTime
+	}
+
+	return
+}
+
+/***********************************************/
+
+// Parse the bandwidth in the form of "<number> <unit>", e.g., "500 KB/s", into bytes per second.
+func ParseBandwidth(str string) (int64, error) {
+	var (
+		val  float64
+		unit string
+	)
+
+	if n, _ := fmt.Sscanf(str, "%f %s", &val, &unit); n != 2 || val <= 0 {
+		return 0, fmt.Errorf(`bandwidth "%s" is not in the form of "<number> <unit>"`, str)
+	}
+
+	switch strings.ToUpper(strings.TrimSuffix(unit, "/s")) {
+	case "B":
+		return int64(val), nil
+	case "KB":
+		return int64(val * network.ONE_KILOBYTE), nil
+	case "MB":
+		return int64(val * network.ONE_MEGABYTE), nil
+	case "GB":
+		return int64(val * network.ONE_GIGABYTE), nil
+	default:
+		return 0, fmt.Errorf(`unknown unit "%s" of bandwidth, which must be B/s, KB/s, MB/s or GB/s`, unit)
+	}
+}
+
+/***********************************************/
+
+// Get the first epoch (aligned to the interval of the resource) and the last epoch of the task.
+func (task Task) Arc(stTime, edTime datetime.Time) (ts, te datetime.Time) {
+	rs := rsMap[task.Type]
+	ts = stTime.Sub(datetime.Seconds2Time(float64(task.Backward)))
+	te = edTime.Add(datetime.Seconds2Time(float64(task.Forward)))
+	ts.ConvertSelf(rs.TimeSys)
+	te.ConvertSelf(rs.TimeSys)
+	ts = rs.Interval.Floor(ts)
+	return
+}
+
+/***********************************************/
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"godog/network"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+/***** CONSTANT ********************************/
+
+const (
+	DAEMON_TICK        = 30 * time.Second // interval of checking the schedules and the pending jobs
+	DAEMON_BACKOFF_MIN = time.Minute      // delay of the first retry of a job not done
+	DAEMON_BACKOFF_MAX = 2 * time.Hour    // maximum delay of retries, which doubles after each failure
+)
+
+/***** STRUCT **********************************/
+
+// A job not done yet in the daemon mode, which is retried with backoff.
+type tPendingJob struct {
+	job   Job
+	task  int       // index of the task
+	fails int       // number of failed rounds
+	next  time.Time // time of the next round
+}
+
+/***** FUNCTION ********************************/
+
+// Get the delay before the next round of a job after its fails-th failure.
+func backoff(fails int) time.Duration {
+	delay := DAEMON_BACKOFF_MIN
+
+	for i := 1; i < fails && delay < DAEMON_BACKOFF_MAX; i++ {
+		delay *= 2
+	}
+
+	return min(delay, DAEMON_BACKOFF_MAX)
+}
+
+/***********************************************/
+
+// Check whether the job has been done according to the state, only the existence and size of the file are checked.
+// The file from a fallback is not done, since it is to be upgraded.
+func isDone(job *Job) bool {
+	state, ok := stateDB.Get(job.Path)
+
+	if !ok || state.Status != STATUS_DONE || job.CanUpgrade(state.Product) {
+		return false
+	}
+
+	info, err := os.Stat(state.File)
+	return err == nil && info.Size() == state.Size
+}
+
+/***********************************************/
+
+// Run as a daemon until ctx is canceled. Each task is scanned on its schedule, with the arc relative to now,
+// and the jobs not done yet are pending, which are retried with backoff until done or out of the arc.
+// The report, if any, is written after each round. The jobs done are always skipped and the missing ones are retried,
+// so it cannot be used with -resume.
+func daemon(ctx context.Context, reportFile string) error {
+	var (
+		mutex    sync.Mutex
+		lastScan = make([]time.Time, len(cfg.Tasks))
+		pending  = make(map[string]*tPendingJob) // key: path of the job
+	)
+
+	setupNetwork()
+	defer network.CloseSessions()
+
+	for {
+		now := time.Now()
+		stTime, edTime := cfg.Window()
+
+		// 1. scan the tasks due
+		for idx, task := range cfg.Tasks {
+			if !lastScan[idx].IsZero() && now.Sub(lastScan[idx]) < task.Period {
+				continue
+			}
+
+			lastScan[idx] = now
+			inArc := make(map[string]bool)
+			ts, te := task.Arc(stTime, edTime)
+
+			genJobs(task, ts, te, func(job Job) bool {
+				inArc[job.Path] = true
+
+				if _, ok := pending[job.Path]; !ok && (job.Force || job.Sync || !isDone(&job)) {
+					pending[job.Path] = &tPendingJob{job: job, task: idx, next: now}
+				}
+
+				return true
+			})
+
+			// the jobs out of the arc are given up
+			for path, p := range pending {
+				if p.task == idx && !inArc[path] {
+					log.Printf("[error] %s is out of the arc, given up after %d rounds", path, p.fails)
+					delete(pending, path)
+				}
+			}
+		}
+
+		// 2. do the pending jobs due
+		var jobs []Job
+
+		for _, p := range pending {
+			if !p.next.After(now) {
+				jobs = append(jobs, p.job)
+			}
+		}
+
+		sort.Slice(jobs, func(i, j int) bool {
+			return jobs[i].Path < jobs[j].Path
+		})
+
+		if len(jobs) != 0 {
+			// the remote directories and manifests may be updated since the last round
+			listCache.Reset()
+			manifestCache.Reset()
+
+			runJobs(ctx, min(len(jobs), cfg.GoNum), func(push func(job Job) bool) {
+				for _, job := range jobs {
+					if !push(job) {
+						return
+					}
+				}
+			}, func(job *Job, status string) {
+				mutex.Lock()
+				defer mutex.Unlock()
+
+				if p, ok := pending[job.Path]; !ok {
+					return
+				} else if status == STATUS_DONE || status == STATUS_EXISTS {
+					delete(pending, job.Path)
+				} else {
+					p.fails++
+					p.next = time.Now().Add(backoff(p.fails))
+				}
+			})
+
+			log.Printf("[info] round finished, %d jobs done or tried, %d pending", len(jobs), len(pending))
+
+			if len(reportFile) != 0 {
+				if err := report.Write(reportFile); err != nil {
+					log.Println("[error] failed to write the report.", err)
+				}
+
+				report.Reset()
+			}
+		}
+
+		// 3. wait for the next tick
+		select {
+		case <-ctx.Done():
+			log.Println("[info] daemon stopped")
+			return nil
+		case <-time.After(DAEMON_TICK):
+		}
+	}
+}
+
+/***********************************************/
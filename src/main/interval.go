@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"godog/datetime"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/***** CONSTANT ********************************/
+
+type IntervalUnit uint8
+
+const (
+	INTERVAL_SECOND IntervalUnit = iota // fixed length, aligned to multiples of the length since 01-Jan-1601
+	INTERVAL_WEEK                       // GPS week, aligned to Sunday
+	INTERVAL_MONTH                      // calendar month, aligned to the 1st day of the month
+	INTERVAL_YEAR                       // calendar year, aligned to 1st January
+)
+
+/***********************************************/
+
+// mjd of the beginning of GPS week 0 (06-Jan-1980)
+const _MJD_GPS_WEEK0 int32 = 44244
+
+/***********************************************/
+
+var name2IntervalUnit map[string]IntervalUnit = map[string]IntervalUnit{
+	"s":         INTERVAL_SECOND,
+	"sec":       INTERVAL_SECOND,
+	"second":    INTERVAL_SECOND,
+	"seconds":   INTERVAL_SECOND,
+	"min":       INTERVAL_SECOND,
+	"minute":    INTERVAL_SECOND,
+	"minutes":   INTERVAL_SECOND,
+	"h":         INTERVAL_SECOND,
+	"hour":      INTERVAL_SECOND,
+	"hours":     INTERVAL_SECOND,
+	"d":         INTERVAL_SECOND,
+	"day":       INTERVAL_SECOND,
+	"days":      INTERVAL_SECOND,
+	"week":      INTERVAL_WEEK,
+	"weeks":     INTERVAL_WEEK,
+	"gps week":  INTERVAL_WEEK,
+	"gps weeks": INTERVAL_WEEK,
+	"month":     INTERVAL_MONTH,
+	"months":    INTERVAL_MONTH,
+	"year":      INTERVAL_YEAR,
+	"years":     INTERVAL_YEAR,
+}
+
+var name2Seconds map[string]int = map[string]int{
+	"s":       1,
+	"sec":     1,
+	"second":  1,
+	"seconds": 1,
+	"min":     int(datetime.MINUTE2SECOND),
+	"minute":  int(datetime.MINUTE2SECOND),
+	"minutes": int(datetime.MINUTE2SECOND),
+	"h":       int(datetime.HOUR2SECOND),
+	"hour":    int(datetime.HOUR2SECOND),
+	"hours":   int(datetime.HOUR2SECOND),
+	"d":       int(datetime.DAY2SECOND),
+	"day":     int(datetime.DAY2SECOND),
+	"days":    int(datetime.DAY2SECOND),
+}
+
+/***** STRUCT **********************************/
+
+// Interval between two adjacent epochs of a resource, e.g., 86400 (seconds), "1 month" or "1 GPS week".
+type Interval struct {
+	Num  int          // number of seconds for INTERVAL_SECOND, otherwise number of weeks/months/years
+	Unit IntervalUnit // unit of the interval
+}
+
+/***** FUNCTION ********************************/
+
+// Parse the interval from a json value, which is either a number of seconds or a string like "1 month".
+func ParseInterval(val any) (iv Interval, err error) {
+	switch v := val.(type) {
+	case float64:
+		if v <= 0 || v != math.Trunc(v) || v > math.MaxInt32 {
+			return iv, fmt.Errorf("invalid number of seconds %v", v)
+		}
+
+		return Interval{Num: int(v), Unit: INTERVAL_SECOND}, nil
+	case string:
+		subs := strings.Fields(strings.ToLower(v))
+
+		if len(subs) == 0 {
+			return iv, fmt.Errorf("empty interval")
+		}
+
+		iv.Num, err = strconv.Atoi(subs[0])
+
+		if err != nil || iv.Num <= 0 {
+			return iv, fmt.Errorf(`invalid number in "%s"`, v)
+		}
+
+		if len(subs) == 1 {
+			return iv, nil
+		}
+
+		name := strings.Join(subs[1:], " ")
+		unit, ok := name2IntervalUnit[name]
+
+		if !ok {
+			return iv, fmt.Errorf(`invalid unit in "%s"`, v)
+		}
+
+		iv.Unit = unit
+
+		if unit == INTERVAL_SECOND {
+			iv.Num *= name2Seconds[name]
+		}
+
+		return iv, nil
+	default:
+		return iv, fmt.Errorf("invalid type of interval")
+	}
+}
+
+/***********************************************/
+
+// Get the epoch aligned to the interval which is the closest one not later than t.
+func (iv Interval) Floor(t datetime.Time) datetime.Time {
+	switch iv.Unit {
+	case INTERVAL_WEEK:
+		year, month, day := t.Date()
+		mjd := datetime.Date2Date(year, month, day).Mjd()
+		num := int32(iv.Num) * int32(datetime.WEEK2DAY)
+		mjd -= _MJD_GPS_WEEK0
+		mjd = mjd - (mjd%num+num)%num + _MJD_GPS_WEEK0
+		year, month, day = datetime.Mjd2Date(mjd).Date()
+		return datetime.DateTime2Time(t.Sys(), year, month, day, 0, 0, 0)
+	case INTERVAL_MONTH:
+		year, month, _ := t.Date()
+		idx := year*12 + int32(month) - 1
+		idx -= (idx%int32(iv.Num) + int32(iv.Num)) % int32(iv.Num)
+		return datetime.DateTime2Time(t.Sys(), idx/12, uint8(idx%12)+1, 1, 0, 0, 0)
+	case INTERVAL_YEAR:
+		year := t.Year()
+		year -= (year%int32(iv.Num) + int32(iv.Num)) % int32(iv.Num)
+		return datetime.DateTime2Time(t.Sys(), year, 1, 1, 0, 0, 0)
+	default:
+		dt := datetime.Seconds2Time(float64(iv.Num))
+		ordDec := float64(int32(t.OrdTotal()/dt.OrdTotal())) * dt.OrdTotal()
+		ordInt := int32(ordDec)
+		ordDec -= float64(ordInt)
+
+		if -datetime.TIME_EPSILON < ordDec && ordDec < datetime.TIME_EPSILON {
+			ordDec = 0
+		}
+
+		return datetime.Ord2Time(t.Sys(), ordInt, ordDec)
+	}
+}
+
+/***********************************************/
+
+// Get the next epoch after t.
+func (iv Interval) Next(t datetime.Time) datetime.Time {
+	switch iv.Unit {
+	case INTERVAL_WEEK:
+		year, month, day, hour, minute, second := t.DateTime()
+		year, month, day = datetime.Date2Date(year, month, day).Add(int32(iv.Num) * int32(datetime.WEEK2DAY)).Date()
+		return datetime.DateTime2Time(t.Sys(), year, month, day, hour, minute, second)
+	case INTERVAL_MONTH:
+		return t.AddMonth(int32(iv.Num))
+	case INTERVAL_YEAR:
+		return t.AddYear(int32(iv.Num))
+	default:
+		return t.Add(datetime.Seconds2Time(float64(iv.Num)))
+	}
+}
+
+/***********************************************/
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"godog/datetime"
+	"testing"
+)
+
+func TestIntervalMonth(t *testing.T) {
+	gpst := func(year int32, month, day, hour uint8) datetime.Time {
+		return datetime.DateTime2Time(datetime.TIME_SYS_GPST, year, month, day, hour, 0, 0)
+	}
+
+	for _, tc := range []struct {
+		val       any
+		t         datetime.Time
+		wantFloor datetime.Time
+		wantNext  datetime.Time
+	}{
+		{"1 month", gpst(2021, 12, 15, 6), gpst(2021, 12, 1, 0), gpst(2022, 1, 15, 6)},
+		{"1 month", gpst(2022, 1, 31, 0), gpst(2022, 1, 1, 0), gpst(2022, 2, 28, 0)},
+		{"3 months", gpst(2021, 11, 30, 0), gpst(2021, 10, 1, 0), gpst(2022, 2, 28, 0)},
+		{"6 months", gpst(2022, 2, 1, 0), gpst(2022, 1, 1, 0), gpst(2022, 8, 1, 0)},
+		{"1 year", gpst(2024, 2, 29, 0), gpst(2024, 1, 1, 0), gpst(2025, 2, 28, 0)},
+	} {
+		iv, err := ParseInterval(tc.val)
+
+		if err != nil {
+			t.Fatalf("ParseInterval(%v): %s", tc.val, err)
+		}
+
+		if got := iv.Floor(tc.t); got.Format("{D} {T}") != tc.wantFloor.Format("{D} {T}") {
+			t.Errorf("%v Floor(%s) = %s, want %s", tc.val, tc.t.Format("{D} {T}"), got.Format("{D} {T}"), tc.wantFloor.Format("{D} {T}"))
+		}
+
+		if got := iv.Next(tc.t); got.Format("{D} {T}") != tc.wantNext.Format("{D} {T}") {
+			t.Errorf("%v Next(%s) = %s, want %s", tc.val, tc.t.Format("{D} {T}"), got.Format("{D} {T}"), tc.wantNext.Format("{D} {T}"))
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"godog/network"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+/***** VARIABLE ********************************/
+
+var listCache tCache[[]string] // key: username and url of the remote directory
+
+/***** FUNCTION ********************************/
+
+// List the names of files in the remote directory with the downloader matching the url of the source.
+func list(source network.NetworkInfo) ([]string, network.TaskError) {
+	d, err := network.GetDownloader(source.Url)
+
+	if err != nil {
+		return nil, network.NewTaskError(err, false)
+	}
+
+	return d.List(&source)
+}
+
+/***********************************************/
+
+// Get the urls of the job from the source. If the file name is a glob pattern, e.g., "ABMF00GLP_?_*_MO.crx.gz",
+// or a regular expression, the remote directory is listed once, and the urls of matched files are sorted by name.
+func matchURL(job *Job, source network.NetworkInfo) ([]string, error) {
+	rawURL := getPathURL(job.Time, job.Name, source.Url)
+	idx := strings.LastIndexByte(rawURL, '/')
+	dir, pattern := rawURL[:idx+1], rawURL[idx+1:]
+
+	if !source.Regex && !strings.ContainsAny(pattern, "*?[") {
+		return []string{rawURL}, nil
+	}
+
+	var (
+		re  *regexp.Regexp
+		err error
+	)
+
+	if source.Regex {
+		if re, err = regexp.Compile("^(?:" + pattern + ")$"); err != nil {
+			return nil, network.NewTaskError(fmt.Errorf(`invalid regular expression "%s", %s`, pattern, err), false)
+		}
+	} else if _, err = path.Match(pattern, ""); err != nil {
+		return nil, network.NewTaskError(fmt.Errorf(`invalid glob pattern "%s", %s`, pattern, err), false)
+	}
+
+	source.Url = dir
+	names, err := listCache.Get(source.UserName+"@"+dir, func() ([]string, error) {
+		if names, tErr := list(source); tErr != nil {
+			return nil, tErr
+		} else {
+			return names, nil
+		}
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	var urls []string
+
+	for _, name := range names {
+		if matched, _ := path.Match(pattern, name); (re == nil && matched) || (re != nil && re.MatchString(name)) {
+			urls = append(urls, dir+name)
+		}
+	}
+
+	if len(urls) == 0 {
+		return nil, network.NewTaskError(fmt.Errorf(`%w, no file matches "%s" in %s`, network.ErrNotFound, pattern, dir), false)
+	}
+
+	sort.Strings(urls)
+	return urls, nil
+}
+
+/***********************************************/
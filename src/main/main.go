@@ -10,8 +10,15 @@ the LICENSE file found in the root directory of this source tree.
 package main
 
 import (
+	"context"
 	"flag"
+	"godog/network"
 	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
 )
 
 /***** VARIABLE ********************************/
@@ -19,21 +26,40 @@ import (
 var (
 	rsMap         map[string]Resource         = make(map[string]Resource)
 	targetInfoMap map[string]*TargetInfoArray = make(map[string]*TargetInfoArray)
+	siteLogMap    map[string]*SiteLogArray    = make(map[string]*SiteLogArray)
 	cfg           Config
 	jobNum        int
+	stateDB       *StateDB
+	ifResume      bool
+	report        Report
 )
 
 /***** FUNCTION ********************************/
 
 func main() {
+	// the secrets in the resources are never written into the logs
+	log.SetOutput(network.NewRedactWriter(os.Stderr))
 	log.Println("[info] GoDOG started")
 
 	// 1. parse command-line options
-	var rsFile, cfgFile string
+	var rsFile, cfgFile, stateFile, reportFile string
+	var ifDaemon bool
 	flag.StringVar(&rsFile, "rs", "./resource.json", "the path of the resource file (json)")
 	flag.StringVar(&cfgFile, "cfg", "./config.json", "the path of the config file (json)")
+	flag.StringVar(&stateFile, "state", "", "the path of the state file (json lines), default is next to the config file")
+	flag.BoolVar(&ifResume, "resume", false, "skip the jobs done or permanently missing in the previous run")
+	flag.StringVar(&reportFile, "report", "", "the path of the report of job outcomes, in CSV format if the extension is .csv, otherwise JSON")
+	flag.BoolVar(&ifDaemon, "daemon", false, "run repeatedly on the schedules of tasks, with the arc relative to now, e.g., \"now-3d\"")
 	flag.Parse()
 
+	if ifResume && ifDaemon {
+		log.Fatalln("[fatal] -resume cannot be used with -daemon, in which the jobs done are always skipped and the missing ones are retried")
+	}
+
+	if len(stateFile) == 0 {
+		stateFile = strings.TrimSuffix(cfgFile, filepath.Ext(cfgFile)) + ".state.jsonl"
+	}
+
 	// 2. parse the resource file
 	log.Println("[info] parsing the resource file (json)...")
 
@@ -53,11 +79,38 @@ func main() {
 	log.Println("[info] finished parsing the config file (json)")
 	log.Println("[info] job num:", jobNum)
 
-	// 4. process
-	if err := process(); err != nil {
+	// 4. open the state file
+	var err error
+
+	if stateDB, err = OpenStateDB(stateFile); err != nil {
+		log.Fatalln("[fatal] error in the state file.", err)
+	}
+
+	defer stateDB.Close()
+
+	// 5. process, which stops gracefully on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if ifDaemon {
+		log.Println("[info] running as a daemon")
+
+		if err = daemon(ctx, reportFile); err != nil {
+			log.Fatalln("[fatal] error in the daemon.", err)
+		}
+	} else if err = process(ctx); err != nil {
 		log.Fatalln("[fatal] error in processing tasks.", err)
 	}
 
+	// 6. write the report
+	if len(reportFile) != 0 && !ifDaemon {
+		if err = report.Write(reportFile); err != nil {
+			log.Println("[error] failed to write the report.", err)
+		} else {
+			log.Println("[info] report written to", reportFile)
+		}
+	}
+
 	log.Println("[info] finished")
 }
 
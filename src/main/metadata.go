@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"godog/crx2rnx"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/***** CONSTANT ********************************/
+
+const (
+	METADATA_XYZ_TOLERANCE = 10.0  // tolerance of the approximate position in meters
+	METADATA_ECC_TOLERANCE = 0.001 // tolerance of the antenna eccentricity in meters
+)
+
+/***** STRUCT **********************************/
+
+// Metadata of a station for the downloaded observation file, which are taken from the equipment installed at the epoch
+// in the site log if given, or from the information file, i.e., the latest site information.
+type Metadata struct {
+	Name       string          `json:"name"`
+	Epoch      string          `json:"epoch"`
+	File       string          `json:"file"`
+	Source     string          `json:"source"` // "site log" or "site information"
+	RcvType    string          `json:"receiver type"`
+	RcvSN      string          `json:"receiver serial number"`
+	RcvFirm    string          `json:"receiver firmware"`
+	AntType    string          `json:"antenna type"`
+	AntSN      string          `json:"antenna serial number"`
+	AntUNE     [3]float64      `json:"antenna marker une"`
+	XYZ        [3]float64      `json:"xyz"`
+	Header     *crx2rnx.Header `json:"rinex header"`
+	Mismatches []string        `json:"mismatches"` // differences between the site information and the RINEX header
+}
+
+/***** FUNCTION ********************************/
+
+// Compare the site information with the RINEX header, in which the empty or zero fields are skipped.
+func compareHeader(info TargetInfo, hdr *crx2rnx.Header) []string {
+	var mismatches []string
+
+	check := func(key, expected, actual string) {
+		if len(expected) != 0 && len(actual) != 0 && !strings.EqualFold(expected, actual) {
+			mismatches = append(mismatches, fmt.Sprintf(`%s: "%s" in the site information, "%s" in the header`, key, expected, actual))
+		}
+	}
+
+	// the radome in columns 17-20 is not in the site information
+	antType := strings.TrimSpace(hdr.AntType[:min(16, len(hdr.AntType))])
+
+	check("receiver type", info.RcvType, hdr.RcvType)
+	check("receiver serial number", info.RcvSN, hdr.RcvSerial)
+	check("antenna type", info.AntType, antType)
+	check("antenna serial number", info.AntSN, hdr.AntSerial)
+
+	if hdr.XYZ != [3]float64{} && info.XYZ != [3]float64{} {
+		if d := math.Sqrt(math.Pow(hdr.XYZ[0]-info.XYZ[0], 2) + math.Pow(hdr.XYZ[1]-info.XYZ[1], 2) +
+			math.Pow(hdr.XYZ[2]-info.XYZ[2], 2)); d > METADATA_XYZ_TOLERANCE {
+			mismatches = append(mismatches, fmt.Sprintf("approximate position: %.3f m from the site information", d))
+		}
+	}
+
+	// the eccentricity is up/north/east in the site information, and height/east/north in the header
+	une := [3]float64{hdr.AntHEN[0], hdr.AntHEN[2], hdr.AntHEN[1]}
+
+	for i := range une {
+		if math.Abs(une[i]-info.AntUNE[i]) > METADATA_ECC_TOLERANCE {
+			mismatches = append(mismatches, fmt.Sprintf("antenna eccentricity (UNE): %.4f %.4f %.4f in the site information, %.4f %.4f %.4f in the header",
+				info.AntUNE[0], info.AntUNE[1], info.AntUNE[2], une[0], une[1], une[2]))
+			break
+		}
+	}
+
+	return mismatches
+}
+
+/***********************************************/
+
+// Read the RINEX header of the saved file if it was not got while converting, e.g., for the files not compressed.
+func readHeader(job *Job) *crx2rnx.Header {
+	if job.Header != nil {
+		return job.Header
+	}
+
+	if ext := filepath.Ext(job.File); strings.EqualFold(ext, ".gz") || strings.EqualFold(ext, ".Z") {
+		return nil
+	}
+
+	fp, err := os.Open(job.File)
+
+	if err != nil {
+		return nil
+	}
+
+	defer fp.Close()
+
+	hdr, _ := crx2rnx.ReadHeader(fp)
+	return hdr
+}
+
+/***********************************************/
+
+// Write the metadata of the station for the job, with the mismatches against the RINEX header, which are also returned.
+func writeMetadata(job *Job) ([]string, error) {
+	info, ok := targetInfoMap[job.Type].Get(job.Name)
+
+	if !ok {
+		return nil, fmt.Errorf(`target "%s" not found in the information file`, job.Name)
+	}
+
+	source := "site information"
+
+	if logs, ok := siteLogMap[job.Type]; ok {
+		if site, ok := logs.Get(job.Name); ok {
+			if info, ok = site.At(job.Time, info); ok {
+				source = "site log"
+			}
+		}
+	}
+
+	meta := Metadata{
+		Name:    info.Name,
+		Epoch:   job.Epoch(),
+		File:    job.File,
+		Source:  source,
+		RcvType: info.RcvType,
+		RcvSN:   info.RcvSN,
+		RcvFirm: info.RcvFirm,
+		AntType: info.AntType,
+		AntSN:   info.AntSN,
+		AntUNE:  info.AntUNE,
+		XYZ:     info.XYZ,
+		Header:  readHeader(job),
+	}
+
+	if meta.Header != nil {
+		meta.Mismatches = compareHeader(info, meta.Header)
+	}
+
+	os.MkdirAll(filepath.Dir(job.Meta), 0775)
+	fp, err := os.Create(job.Meta + ".tmp")
+
+	if err != nil {
+		return nil, err
+	}
+
+	ecr := json.NewEncoder(fp)
+	ecr.SetIndent("", "    ")
+	err = ecr.Encode(&meta)
+	fp.Close()
+
+	if err == nil {
+		err = os.Rename(job.Meta+".tmp", job.Meta)
+	}
+
+	if err != nil {
+		os.Remove(job.Meta + ".tmp")
+		return nil, err
+	}
+
+	return meta.Mismatches, nil
+}
+
+/***********************************************/
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"godog/crx2rnx"
+	"godog/datetime"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteMetadataSiteLog(t *testing.T) {
+	// the equipment was replaced on 2020-06-01, and the information file has the current one
+	targetInfoMap["test_obs"] = &TargetInfoArray{Array: []TargetInfo{{
+		Name: "WUHN00CHN", RcvType: "SEPT POLARX5", RcvSN: "4501", AntType: "TRM59800.00", AntSN: "5001", AntUNE: [3]float64{0.1, 0, 0},
+	}}}
+	siteLogMap["test_obs"] = &SiteLogArray{Array: []SiteLog{{
+		Name: "WUHN00CHN",
+		Receivers: []LogReceiver{
+			{Type: "TRIMBLE NETR9", Serial: "3001", Installed: "2015-01-01T00:00:00Z", Removed: "2020-06-01T00:00:00Z"},
+			{Type: "SEPT POLARX5", Serial: "4501", Installed: "2020-06-01T00:00:00Z"},
+		},
+		Antennas: []LogAntenna{
+			{Type: "LEIAR25.R4", Serial: "2001", Installed: "2015-01-01T00:00:00Z", Removed: "2020-06-01T00:00:00Z"},
+			{Type: "TRM59800.00", Serial: "5001", UNE: [3]float64{0.1, 0, 0}, Installed: "2020-06-01T00:00:00Z"},
+		},
+	}}}
+
+	defer delete(targetInfoMap, "test_obs")
+	defer delete(siteLogMap, "test_obs")
+
+	// the header of the file in 2019 has the old equipment
+	oldHeader := crx2rnx.Header{RcvType: "TRIMBLE NETR9", RcvSerial: "3001", AntType: "LEIAR25.R4      LEIT", AntSerial: "2001"}
+
+	for _, tc := range []struct {
+		name       string
+		time       datetime.Time
+		wantRcv    string
+		wantAnt    string
+		wantSource string
+		wantMis    int
+	}{
+		{"before the change", datetime.DateTime2Time(datetime.TIME_SYS_GPST, 2019, 3, 1, 0, 0, 0), "TRIMBLE NETR9", "LEIAR25.R4", "site log", 0},
+		{"at the change", datetime.DateTime2Time(datetime.TIME_SYS_UTC, 2020, 6, 1, 0, 0, 0), "SEPT POLARX5", "TRM59800.00", "site log", 5},
+		{"before the history", datetime.DateTime2Time(datetime.TIME_SYS_GPST, 2010, 1, 1, 0, 0, 0), "SEPT POLARX5", "TRM59800.00", "site information", 5},
+	} {
+		header := oldHeader
+		job := Job{Type: "test_obs", Name: "WUHN00CHN", Time: tc.time, Header: &header, Meta: filepath.Join(t.TempDir(), "WUHN00CHN.json")}
+		mismatches, err := writeMetadata(&job)
+
+		if err != nil {
+			t.Fatalf("%s: writeMetadata: %s", tc.name, err)
+		}
+
+		var meta Metadata
+		buf, _ := os.ReadFile(job.Meta)
+
+		if err = json.Unmarshal(buf, &meta); err != nil {
+			t.Fatalf("%s: invalid metadata file, %s", tc.name, err)
+		}
+
+		if meta.RcvType != tc.wantRcv || meta.AntType != tc.wantAnt || meta.Source != tc.wantSource {
+			t.Errorf("%s: got %s, %s from %s, want %s, %s from %s", tc.name, meta.RcvType, meta.AntType, meta.Source, tc.wantRcv, tc.wantAnt, tc.wantSource)
+		}
+
+		if len(mismatches) != tc.wantMis {
+			t.Errorf("%s: got mismatches %q, want %d", tc.name, mismatches, tc.wantMis)
+		}
+	}
+}
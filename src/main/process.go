@@ -1,6 +1,10 @@
 package main
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"godog/crx2rnx"
 	"godog/datetime"
@@ -11,24 +15,91 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 /***** STRUCT **********************************/
 
 type Job struct {
-	Type  string
-	Time  datetime.Time
-	Name  string
-	Path  string
-	Unzip bool
-	Force bool
-	Index int
-	IsTmp bool
+	Type      string
+	Time      datetime.Time
+	Name      string
+	Path      string
+	Unzip     bool
+	Force     bool
+	Compact   bool
+	Sync      bool
+	Index     int
+	IsTmp     bool
+	IsMissing bool             // whether the file is missing in all sources
+	File      string           // path of the saved file
+	Size      int64            // size of the saved file
+	Sum       string           // sha256 checksum of the saved file
+	Url       string           // url of the source used or tried last
+	Bytes     int64            // size of the downloaded data in all attempts
+	Host      string           // host of the first source, used for scheduling
+	Remote    network.FileInfo // size and modification time of the remote file, only got in the sync mode
+	Types     []string         // types in the order of quality, i.e., the type of the task and its fallbacks
+	Product   string           // type of the resource used or tried last
+	Meta      string           // path of the metadata file of the target, optional
+	Header    *crx2rnx.Header  // station metadata in the RINEX header, only got if Meta is not empty
 }
 
+/***** METHOD **********************************/
+
+// Get the epoch of the job in the form of "GPST 2025-12-01 00:00:00".
+func (job *Job) Epoch() string {
+	return datetime.TimeSys2Name[job.Time.Sys()] + " " + job.Time.Format("{D} {T}")
+}
+
+/***********************************************/
+
+// Get the time when the file is expected to be published, i.e., the epoch plus the latency of the resource,
+// and the shortest latency is used if there are fallbacks.
+func (job *Job) PublishTime() datetime.Time {
+	latency := rsMap[job.Type].Latency
+
+	for _, typ := range job.Types {
+		latency = min(latency, rsMap[typ].Latency)
+	}
+
+	return job.Time.Add(datetime.Seconds2Time(latency.Seconds()))
+}
+
+/***********************************************/
+
+// Check whether the file should have been published.
+func (job *Job) IsPublished() bool {
+	return !datetime.Now2Time(job.Time.Sys()).Lt(job.PublishTime())
+}
+
+/***********************************************/
+
+// Check whether the file from the product can be upgraded, i.e., the product is a fallback of the job.
+func (job *Job) CanUpgrade(product string) bool {
+	return slices.Index(job.Types, product) > 0
+}
+
+/***********************************************/
+
+// Update the error flags of the job after a source failed.
+func (job *Job) setError(err error) {
+	if tErr, ok := err.(network.TaskError); ok {
+		job.IsTmp = job.IsTmp || tErr.IsTemporary()
+	}
+
+	job.IsMissing = job.IsMissing && network.IsNotFound(err)
+}
+
+/***** VARIABLE ********************************/
+
+// error used to abort the downloading when the processing fails
+var errAbort = errors.New("aborted by the processing")
+
 /***** FUNCTION ********************************/
 
 func getPathURL(t datetime.Time, name, template string) string {
@@ -85,121 +156,267 @@ func getPathURL(t datetime.Time, name, template string) string {
 
 /***********************************************/
 
-func doJob(job *Job) (err error) {
-	if _, err = os.Stat(job.Path); err == nil && !job.Force {
-		return io.EOF
+// Download the file with the downloader matching the url of the source.
+func download(netTask *network.NetworkTask) network.TaskError {
+	d, err := network.GetDownloader(netTask.Source.Url)
+
+	if err != nil {
+		return network.NewTaskError(err, false)
+	}
+
+	return d.Download(netTask)
+}
+
+/***********************************************/
+
+// Download the file and write it into desFile, the data are decompressed and converted on the fly.
+// If sum is not empty, the downloaded data are saved and verified by it at first, and then decompressed and converted,
+// so that no corrupted data are passed to the decoders.
+func fetch(job *Job, netTask *network.NetworkTask, desFile, sum string) (err error) {
+	if len(sum) != 0 {
+		return fetchVerified(job, netTask, desFile, sum)
 	}
 
 	var (
-		dir                      = filepath.Dir(job.Path)
-		netTask                  = network.NetworkTask{Continue: true}
-		tErr                     network.TaskError
-		srcFile, desFile, extZip string
-		ifZip                    bool
+		pr, pw = io.Pipe()
+		chErr  = make(chan network.TaskError, 1)
 	)
 
-	os.MkdirAll(dir, 0775)
-	job.Index = 0
-
-	for _, s := range rsMap[job.Type].Sources {
-		// download
-		err = nil
-		netTask.Source.Url = getPathURL(job.Time, job.Name, s.Url)
-		netTask.Source.UserName = s.UserName
-		netTask.Source.Password = s.Password
-		netTask.Path = filepath.ToSlash(filepath.Join(dir, filepath.Base(netTask.Source.Url)))
-		job.Index++
-
-		if netTask.Source.IsFtp() {
-			tErr = network.FTPDownload(&netTask)
-		} else if netTask.Source.IsFtps() {
-			tErr = network.FTPSDownload(&netTask)
-		} else if netTask.Source.IsHttpsCddis() {
-			tErr = network.CDDISDownLoad(&netTask)
-		} else if netTask.Source.IsHttp() {
-			tErr = network.HTTPDownload(&netTask)
-		} else if netTask.Source.IsHttps() {
-			tErr = network.HTTPDownload(&netTask)
+	netTask.Writer = pw
+
+	go func() {
+		tErr := download(netTask)
+		chErr <- tErr
+		pw.CloseWithError(tErr)
+	}()
+
+	// the error of downloading has priority, unless the downloading was aborted because of the processing
+	defer func() {
+		if err != nil {
+			pr.CloseWithError(errAbort)
 		} else {
-			continue
+			io.Copy(io.Discard, pr)
 		}
 
-		if tErr != nil {
-			job.IsTmp = job.IsTmp || tErr.IsTemporary()
+		if tErr := <-chErr; tErr != nil && !errors.Is(tErr, errAbort) {
 			err = tErr
-			os.Remove(netTask.Path)
-			continue
 		}
+	}()
 
-		// uncompress
-		srcFile, desFile = netTask.Path, netTask.Path
-		err = nil
-		extZip = filepath.Ext(srcFile)
+	return convert(job, filepath.Base(netTask.Source.Url), pr, desFile)
+}
 
-		if strings.EqualFold(extZip, ".gz") || strings.EqualFold(extZip, ".Z") {
-			ifZip = true
-		} else {
-			ifZip = false
+/***********************************************/
+
+// Download the file into a temporary file beside desFile, verify it by the checksum, and then decompress and convert it.
+func fetchVerified(job *Job, netTask *network.NetworkTask, desFile, sum string) error {
+	rawHash, err := newHash(sum)
+
+	if err != nil {
+		return err
+	}
+
+	fp, err := os.Create(desFile + ".raw")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(desFile + ".raw")
+	defer fp.Close()
+
+	netTask.Writer = io.MultiWriter(fp, rawHash)
+
+	if tErr := download(netTask); tErr != nil {
+		return tErr
+	}
+
+	if hex.EncodeToString(rawHash.Sum(nil)) != sum {
+		return network.NewTaskError(fmt.Errorf("checksum mismatch of %s", netTask.Source.Url), true)
+	}
+
+	if _, err = fp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	return convert(job, filepath.Base(netTask.Source.Url), fp, desFile)
+}
+
+/***********************************************/
+
+// Decompress and convert the data of the file named name, and write them into desFile.
+func convert(job *Job, name string, reader io.Reader, desFile string) error {
+	var (
+		extZip = filepath.Ext(name)
+		ext    = filepath.Ext(strings.TrimSuffix(name, extZip))
+	)
+
+	fp, err := os.Create(desFile)
+
+	if err != nil {
+		return err
+	}
+
+	defer fp.Close()
+
+	// the checksum is calculated while writing
+	h := sha256.New()
+	writer := io.MultiWriter(fp, h)
+
+	// uncompress
+	if job.Unzip && (strings.EqualFold(extZip, ".gz") || strings.EqualFold(extZip, ".Z")) {
+		if reader, err = unzip.NewReader(reader, extZip); err != nil {
+			return err
 		}
+	} else {
+		ext = extZip
+	}
 
-		if ifZip && job.Unzip {
-			if strings.EqualFold(extZip, ".gz") {
-				desFile = srcFile[:len(srcFile)-3]
-				err = unzip.UnzipGZ(srcFile, desFile)
-			} else if strings.EqualFold(extZip, ".Z") {
-				desFile = srcFile[:len(srcFile)-2]
-				err = unzip.UnzipZ(srcFile, desFile)
-			}
+	// convert from crx to rnx, or from rnx to crx if the observation files are stored compactly
+	if job.Header = nil; len(job.Meta) != 0 {
+		job.Header = new(crx2rnx.Header)
+	}
 
-			if err != nil {
-				os.Remove(srcFile)
-				os.Remove(desFile)
-				continue
-			} else {
-				os.Remove(srcFile)
-			}
+	if !job.Compact && (strings.EqualFold(ext, ".crx") || strings.EqualFold(ext, job.Time.Format(".{02Y}d"))) {
+		err = crx2rnx.CRX2RNXStreamHeader(reader, writer, job.Header)
+	} else if job.Compact && (strings.EqualFold(ext, ".rnx") && strings.HasSuffix(strings.ToUpper(name), "O.RNX"+strings.ToUpper(extZip)) ||
+		strings.EqualFold(ext, job.Time.Format(".{02Y}o"))) {
+		err = crx2rnx.RNX2CRXStreamHeader(reader, writer, job.Header)
+	} else {
+		job.Header = nil
+		_, err = io.Copy(writer, reader)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	job.Size, err = fp.Seek(0, io.SeekCurrent)
+	job.Sum = hex.EncodeToString(h.Sum(nil))
+	return err
+}
+
+/***********************************************/
+
+// Download the file from the source, and save it after decompression and conversion.
+func saveFile(job *Job, source network.NetworkInfo) (err error) {
+	var (
+		netTask = network.NetworkTask{Source: source}
+		desFile = job.Path
+		extZip  = filepath.Ext(source.Url)
+		sum     string
+	)
+
+	netTask.Path = filepath.ToSlash(filepath.Join(filepath.Dir(job.Path), filepath.Base(source.Url)))
+	job.Url = source.Url
+
+	// the compressed file is saved with its extension, if it is not decompressed
+	if !job.Unzip && (strings.EqualFold(extZip, ".gz") || strings.EqualFold(extZip, ".Z")) &&
+		!strings.EqualFold(filepath.Ext(job.Path), extZip) {
+		desFile = job.Path + extZip
+	}
+
+	// get the size and modification time of the remote file, which are recorded for the next sync
+	if job.Sync {
+		job.Remote, _ = stat(source)
+	}
+
+	// get the checksum from the manifest
+	if len(rsMap[job.Product].Checksum) != 0 {
+		if sum, err = getChecksum(job, source); err != nil {
+			return err
 		}
+	}
 
-		// convert from crx to rnx
-		srcFile = desFile
-		err = nil
-		ext := job.Time.Format(".{02Y}d")
+	// download, uncompress and convert
+	err = fetch(job, &netTask, desFile+".tmp", sum)
+	job.Bytes += netTask.Size
 
-		if strings.EqualFold(filepath.Ext(srcFile), ".crx") ||
-			strings.EqualFold(filepath.Ext(srcFile), ext) {
-			desFile = ""
-			err = crx2rnx.CRX2RNX(srcFile, &desFile)
+	if err != nil {
+		os.Remove(desFile + ".tmp")
+		return err
+	}
 
-			if err != nil {
-				os.Remove(srcFile)
-				os.Remove(desFile)
-				continue
-			} else {
-				os.Remove(srcFile)
-			}
+	// rename
+	err = os.Rename(desFile+".tmp", desFile)
+
+	if err != nil {
+		os.Remove(desFile + ".tmp")
+		os.Remove(desFile)
+		return err
+	}
+
+	if !job.Remote.ModTime.IsZero() {
+		os.Chtimes(desFile, time.Now(), job.Remote.ModTime)
+	}
+
+	job.File = desFile
+
+	// the metadata are optional, whose failure is logged only
+	if len(job.Meta) != 0 {
+		if mismatches, err := writeMetadata(job); err != nil {
+			log.Println("[error] failed to write the metadata of", job.Path, err)
+		} else if len(mismatches) != 0 {
+			log.Printf("[error] metadata of %s mismatch the RINEX header, %s", job.Path, strings.Join(mismatches, "; "))
 		}
+	}
 
-		// rename
-		srcFile = desFile
-		desFile = job.Path
-		err = nil
-		ext = filepath.Ext(job.Path)
+	return nil
+}
 
-		if ifZip && !job.Unzip {
-			if !strings.EqualFold(ext, extZip) {
-				desFile = job.Path + extZip
-			}
+/***********************************************/
+
+func doJob(job *Job) (err error) {
+	var (
+		urls  []string
+		types = job.Types
+		state JobState
+	)
+
+	// the existing file from a fallback is kept, while only the better products are tried;
+	// in the sync mode, the existing file is downloaded again if the remote one is updated
+	if _, err = os.Stat(job.Path); err == nil && !job.Force {
+		if state, _ = stateDB.Get(job.Path); state.Status == STATUS_DONE && job.CanUpgrade(state.Product) {
+			types = types[:slices.Index(types, state.Product)]
+		} else if !job.Sync || isUpToDate(job) {
+			return io.EOF
 		}
+	}
 
-		err = os.Rename(srcFile, desFile)
+	os.MkdirAll(filepath.Dir(job.Path), 0775)
+	job.IsMissing = true
 
-		if err != nil {
-			os.Remove(srcFile)
-			os.Remove(desFile)
-			continue
+	if len(types) == len(job.Types) {
+		recordState(job, STATUS_PENDING)
+	}
+
+	for _, typ := range types {
+		job.Product, job.Index = typ, 0
+
+		for _, s := range rsMap[typ].Sources {
+			job.Index++
+
+			// the file name in the url may be a pattern matching several files
+			if urls, err = matchURL(job, s); err != nil {
+				job.setError(err)
+				continue
+			}
+
+			for _, u := range urls {
+				s.Url = u
+
+				if err = saveFile(job, s); err == nil {
+					return nil
+				}
+
+				job.setError(err)
+			}
 		}
+	}
 
-		break
+	if len(types) != len(job.Types) {
+		job.Product, job.Index, job.Url = state.Product, state.Index, state.Url
+		return io.EOF
 	}
 
 	return
@@ -207,80 +424,182 @@ func doJob(job *Job) (err error) {
 
 /***********************************************/
 
-func process() error {
+func recordState(job *Job, status string) {
+	if err := stateDB.Put(job, status); err != nil {
+		log.Println("[error] failed to record the state of", job.Path, err)
+	}
+}
+
+/***********************************************/
+
+// Do the job with retries, then log and record the outcome, which is returned.
+func runJob(job *Job) string {
 	var (
-		wg       sync.WaitGroup
-		goJobNum = min(jobNum, cfg.GoNum)
-		chJobQue = make(chan Job, goJobNum)
+		err      error
+		attempts int
+		status   string
+		msg      string
+		stTime   = time.Now()
 	)
 
-	// distribute jobs
-	go func() {
-		var (
-			ts, te, dt datetime.Time
-			job        Job
-			ordInt     int32
-			ordDec     float64
-		)
+	// skip the jobs done or permanently missing in the previous run,
+	// and the existing files without verified records are downloaded again since they may be partial
+	// but the files from fallbacks are kept and upgraded if the better products are available
+	if ifResume {
+		state, ok := stateDB.Get(job.Path)
+
+		if isDone := stateDB.IsDone(job.Path); isDone && !job.CanUpgrade(state.Product) {
+			job.File, job.Index, job.Url, job.Product = state.File, state.Index, state.Url, state.Product
+			status, msg = STATUS_DONE, fmt.Sprintf("[info] %s was done in the previous run", job.Path)
+		} else if isDone {
+			job.Force = false
+		} else if ok && state.Status == STATUS_MISSING {
+			status, msg = STATUS_MISSING, fmt.Sprintf("[info] %s is permanently missing, skipped", job.Path)
+		} else {
+			job.Force = true
+		}
+	}
 
-		for _, task := range cfg.Tasks {
-			ts = cfg.StTime.Sub(datetime.Seconds2Time(float64(task.Backward)))
-			te = cfg.EdTime.Add(datetime.Seconds2Time(float64(task.Forward)))
-			ts.ConvertSelf(rsMap[task.Type].TimeSys)
-			te.ConvertSelf(rsMap[task.Type].TimeSys)
-			dt = datetime.Seconds2Time(float64(rsMap[task.Type].Interval))
-			ordDec = float64(int32(ts.OrdTotal()/dt.OrdTotal())) * dt.OrdTotal()
-			ordInt = int32(ordDec)
-			ordDec -= float64(ordInt)
-
-			if -datetime.TIME_EPSILON < ordDec && ordDec < datetime.TIME_EPSILON {
-				ordDec = 0
+	// the file within the latency of the resource may not be published yet, then it is tried only once
+	published := job.IsPublished()
+	retryNum := cfg.RetryNum
+
+	if !published {
+		retryNum = 0
+	}
+
+	for ; len(status) == 0 && attempts <= retryNum; attempts++ {
+		if err = doJob(job); err == nil {
+			status = STATUS_DONE
+			msg = fmt.Sprintf("[info] finished to download %s, source index %d, attempt num %d", job.Path, job.Index, attempts+1)
+			recordState(job, STATUS_DONE)
+
+			if len(job.Types) > 1 {
+				msg += ", product " + job.Product
 			}
+		} else if err == io.EOF && job.CanUpgrade(job.Product) {
+			status = STATUS_EXISTS
+			msg = fmt.Sprintf("[info] %s from product %s is kept, no better product available", job.Path, job.Product)
+		} else if err == io.EOF && job.Sync {
+			status = STATUS_EXISTS
+			msg = fmt.Sprintf("[info] %s is up to date", job.Path)
+		} else if err == io.EOF {
+			status = STATUS_EXISTS
+			msg = fmt.Sprintf("[info] %s already exists", job.Path)
+		} else if job.IsMissing && published { // no need to retry
+			status = STATUS_MISSING
+			msg = fmt.Sprintf("[ERROR] failed to download %s, missing in all sources", job.Path)
+			recordState(job, STATUS_MISSING)
+		}
+	}
+
+	if len(status) == 0 && !published {
+		status = STATUS_UNAVAILABLE
+		msg = fmt.Sprintf("[info] %s is not yet available, expected after %s %s", job.Path,
+			datetime.TimeSys2Name[job.Time.Sys()], job.PublishTime().Format("{D} {T}"))
+		recordState(job, STATUS_UNAVAILABLE)
+	} else if len(status) == 0 {
+		status = STATUS_FAILED
+		msg = fmt.Sprintf("[ERROR] failed to download %s, attempt num %d", job.Path, attempts)
+		recordState(job, STATUS_FAILED)
+	}
 
-			ts = datetime.Ord2Time(ts.Sys(), ordInt, ordDec)
-			job.Type, job.Unzip, job.Force, job.Index, job.IsTmp = task.Type, task.IfUnzip, task.IfForce, 0, false
-
-			for job.Time = ts; job.Time.Le(te); job.Time.AddEq(dt) {
-				if len(task.Targets) != 0 {
-					for _, target := range task.Targets {
-						job.Name = target
-						job.Path = getPathURL(job.Time, target, task.Path)
-						chJobQue <- job
-					}
-				} else {
-					job.Path = getPathURL(job.Time, "", task.Path)
-					chJobQue <- job
+	log.Println(msg)
+	report.Add(job, status, attempts, time.Since(stTime), err)
+	return status
+}
+
+/***********************************************/
+
+// Set the limits of FTP/FTPS sessions and hosts, which are shared by goroutines.
+func setupNetwork() {
+	network.SetMaxSessionNum(cfg.SessionNum)
+
+	for host, limit := range cfg.Hosts {
+		network.SetHostLimit(host, limit)
+	}
+}
+
+/***********************************************/
+
+// Get the path of the file of the task. If the observation files are stored compactly,
+// the RINEX extension in the template, e.g., [.rnx] or [.??o], is replaced with the CRINEX one.
+func taskPath(task Task, t datetime.Time, name string) string {
+	path := getPathURL(t, name, task.Path)
+
+	if task.IfCompact {
+		path, _ = crx2rnx.CRXFileName(path)
+	}
+
+	return path
+}
+
+/***********************************************/
+
+// Generate the jobs of the task in the arc, and it stops if push returns false.
+func genJobs(task Task, ts, te datetime.Time, push func(job Job) bool) bool {
+	var job Job
+
+	job.Type, job.Unzip, job.Force, job.Compact = task.Type, task.IfUnzip, task.IfForce, task.IfCompact
+	job.Sync = task.IfSync
+	job.Types = append([]string{task.Type}, task.Fallback...)
+
+	for job.Time = ts; job.Time.Le(te); job.Time = rsMap[task.Type].Interval.Next(job.Time) {
+		if len(task.Targets) != 0 {
+			for _, target := range task.Targets {
+				job.Name = target
+				job.Path = taskPath(task, job.Time, target)
+
+				if len(task.MetaPath) != 0 {
+					job.Meta = getPathURL(job.Time, target, task.MetaPath)
+				}
+
+				if !push(job) {
+					return false
 				}
 			}
+		} else {
+			job.Path = taskPath(task, job.Time, "")
+
+			if !push(job) {
+				return false
+			}
 		}
+	}
+
+	return true
+}
+
+/***********************************************/
+
+// Do the jobs generated by gen with goroutines, and done is called with the status after each job if not nil.
+// When ctx is canceled, the jobs not started are dropped, and it returns after the running ones finish.
+func runJobs(ctx context.Context, goNum int, gen func(push func(job Job) bool), done func(job *Job, status string)) {
+	var (
+		wg     sync.WaitGroup
+		jobQue = NewJobQueue()
+	)
+
+	stop := context.AfterFunc(ctx, jobQue.Abort)
+	defer stop()
 
-		close(chJobQue)
+	// distribute jobs
+	go func() {
+		gen(jobQue.Push)
+		jobQue.Close()
 	}()
 
 	// do jobs
-	for i := 0; i < goJobNum; i++ {
+	for i := 0; i < goNum; i++ {
 		wg.Add(1)
 
 		go func() {
-			var count int
-			var msg string
-
-			for job := range chJobQue {
-				for count = 0; count <= cfg.RetryNum; count++ {
-					if err := doJob(&job); err == nil {
-						msg = fmt.Sprintf("[info] finished to download %s, source index %d, attempt num %d", job.Path, job.Index, count+1)
-						break
-					} else if err == io.EOF {
-						msg = fmt.Sprintf("[info] %s already exists", job.Path)
-						break
-					}
-				}
+			for job, ok := jobQue.Pop(); ok; job, ok = jobQue.Pop() {
+				status := runJob(&job)
 
-				if count > cfg.RetryNum {
-					msg = fmt.Sprintf("[ERROR] failed to download %s, attempt num %d", job.Path, count)
+				if done != nil {
+					done(&job, status)
 				}
-
-				log.Println(msg)
 			}
 
 			wg.Done()
@@ -289,6 +608,28 @@ func process() error {
 
 	// wait for all jobs to complete
 	wg.Wait()
+}
+
+/***********************************************/
+
+func process(ctx context.Context) error {
+	// FTP/FTPS sessions are shared by goroutines, and closed after all jobs
+	setupNetwork()
+	defer network.CloseSessions()
+
+	runJobs(ctx, min(jobNum, cfg.GoNum), func(push func(job Job) bool) {
+		for _, task := range cfg.Tasks {
+			ts, te := task.Arc(cfg.StTime, cfg.EdTime)
+
+			if !genJobs(task, ts, te, push) {
+				return
+			}
+		}
+	}, nil)
+
+	if ctx.Err() != nil {
+		log.Println("[info] interrupted, the jobs not started are skipped")
+	}
 
 	return nil
 }
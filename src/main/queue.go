@@ -0,0 +1,124 @@
+package main
+
+import (
+	"godog/network"
+	"net/url"
+	"sync"
+)
+
+/***** CONSTANT ********************************/
+
+const (
+	QUEUE_SIZE = 256 // maximum number of jobs waiting in the queue
+)
+
+/***** STRUCT **********************************/
+
+// Queue of jobs shared by goroutines, in which the jobs whose hosts are not busy are preferred,
+// so that goroutines do not all wait for one host while other hosts are idle.
+type JobQueue struct {
+	mutex  sync.Mutex
+	cond   *sync.Cond
+	jobs   []Job
+	closed bool
+}
+
+/***** FUNCTION ********************************/
+
+func NewJobQueue() *JobQueue {
+	q := &JobQueue{jobs: make([]Job, 0, QUEUE_SIZE)}
+	q.cond = sync.NewCond(&q.mutex)
+	return q
+}
+
+/***********************************************/
+
+// Get the host of the first source of the job.
+func getJobHost(job *Job) string {
+	if sources := rsMap[job.Type].Sources; len(sources) != 0 {
+		if pURL, err := url.Parse(getPathURL(job.Time, job.Name, sources[0].Url)); err == nil {
+			return pURL.Hostname()
+		}
+	}
+
+	return ""
+}
+
+/***** METHOD **********************************/
+
+// Add a job into the queue, which blocks if the queue is full. It returns false if the queue is closed.
+func (q *JobQueue) Push(job Job) bool {
+	job.Host = getJobHost(&job)
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for len(q.jobs) >= QUEUE_SIZE && !q.closed {
+		q.cond.Wait()
+	}
+
+	if q.closed {
+		return false
+	}
+
+	q.jobs = append(q.jobs, job)
+	q.cond.Broadcast()
+	return true
+}
+
+/***********************************************/
+
+// Close the queue after all jobs are added.
+func (q *JobQueue) Close() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.closed = true
+	q.cond.Broadcast()
+}
+
+/***********************************************/
+
+// Close the queue and drop the jobs waiting, e.g., when shutting down.
+func (q *JobQueue) Abort() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.closed = true
+	q.jobs = nil
+	q.cond.Broadcast()
+}
+
+/***********************************************/
+
+// Take the first job whose host is not busy, or the first job if all hosts are busy.
+// It blocks if the queue is empty, and returns false if the queue is closed and empty.
+func (q *JobQueue) Pop() (Job, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for len(q.jobs) == 0 {
+		if q.closed {
+			return Job{}, false
+		}
+
+		q.cond.Wait()
+	}
+
+	idx := 0
+
+	for i := range q.jobs {
+		if !network.HostBusy(q.jobs[i].Host) {
+			idx = i
+			break
+		}
+	}
+
+	job := q.jobs[idx]
+	q.jobs = append(q.jobs[:idx], q.jobs[idx+1:]...)
+	q.cond.Broadcast()
+
+	return job, true
+}
+
+/***********************************************/
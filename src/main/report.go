@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"godog/network"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/***** CONSTANT ********************************/
+
+const (
+	STATUS_EXISTS = "exists" // the file already exists, and is not downloaded again
+)
+
+/***********************************************/
+
+const (
+	ERROR_CLASS_TEMPORARY = "temporary" // at least one source failed because of temporary errors
+	ERROR_CLASS_PERMANENT = "permanent" // all sources failed because of permanent errors
+)
+
+/***** STRUCT **********************************/
+
+// Outcome of a job, which is written into the report.
+type JobReport struct {
+	Type     string  `json:"type"`
+	Target   string  `json:"target"`
+	Epoch    string  `json:"epoch"`
+	Path     string  `json:"path"`
+	Url      string  `json:"url"`
+	Product  string  `json:"product"` // type of the resource used or tried last
+	Status   string  `json:"status"`
+	Attempts int     `json:"attempts"`
+	Bytes    int64   `json:"bytes"`
+	Duration float64 `json:"duration"` // in seconds
+	ErrClass string  `json:"error class"`
+	Error    string  `json:"error"`
+}
+
+/***********************************************/
+
+type Report struct {
+	mutex sync.Mutex
+	items []JobReport
+}
+
+/***** METHOD **********************************/
+
+func (r *Report) Add(job *Job, status string, attempts int, duration time.Duration, err error) {
+	item := JobReport{
+		Type:     job.Type,
+		Target:   job.Name,
+		Epoch:    job.Epoch(),
+		Path:     job.Path,
+		Url:      network.Redact(job.Url),
+		Product:  job.Product,
+		Status:   status,
+		Attempts: attempts,
+		Bytes:    job.Bytes,
+		Duration: duration.Seconds(),
+	}
+
+	if status == STATUS_DONE {
+		item.Path = job.File
+	}
+
+	if err != nil && status != STATUS_EXISTS {
+		item.Error = network.Redact(err.Error())
+
+		// the file not yet published is not a failure
+		if status == STATUS_UNAVAILABLE {
+			item.ErrClass = ""
+		} else if job.IsTmp {
+			item.ErrClass = ERROR_CLASS_TEMPORARY
+		} else {
+			item.ErrClass = ERROR_CLASS_PERMANENT
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.items = append(r.items, item)
+}
+
+/***********************************************/
+
+// Clear the items written, e.g., after each round in the daemon mode.
+func (r *Report) Reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.items = nil
+}
+
+/***********************************************/
+
+// Write the report into a file, in CSV format if the extension is ".csv", otherwise in JSON format.
+func (r *Report) Write(path string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	sort.SliceStable(r.items, func(i, j int) bool {
+		if r.items[i].Type != r.items[j].Type {
+			return r.items[i].Type < r.items[j].Type
+		} else if r.items[i].Epoch != r.items[j].Epoch {
+			return r.items[i].Epoch < r.items[j].Epoch
+		} else {
+			return r.items[i].Target < r.items[j].Target
+		}
+	})
+
+	fp, err := os.Create(path)
+
+	if err != nil {
+		return err
+	}
+
+	defer fp.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		writer := csv.NewWriter(fp)
+		writer.Write([]string{"type", "target", "epoch", "path", "url", "product", "status",
+			"attempts", "bytes", "duration", "error class", "error"})
+
+		for _, item := range r.items {
+			writer.Write([]string{item.Type, item.Target, item.Epoch, item.Path, item.Url, item.Product, item.Status,
+				strconv.Itoa(item.Attempts), strconv.FormatInt(item.Bytes, 10),
+				strconv.FormatFloat(item.Duration, 'f', 3, 64), item.ErrClass, item.Error})
+		}
+
+		writer.Flush()
+		return writer.Error()
+	} else {
+		encoder := json.NewEncoder(fp)
+		encoder.SetIndent("", "    ")
+		return encoder.Encode(r.items)
+	}
+}
+
+/***********************************************/
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"godog/datetime"
 	"godog/network"
+	"net/url"
 	"os"
+	"time"
 )
 
 /***** STRUCT **********************************/
@@ -13,7 +15,9 @@ import (
 type tResource struct {
 	Sources  []network.NetworkInfo `json:"sources"`
 	TimeSys  string                `json:"time system"`
-	Interval int                   `json:"interval"`
+	Interval any                   `json:"interval"`
+	Checksum string                `json:"checksum"`
+	Latency  any                   `json:"latency"`
 }
 
 /***********************************************/
@@ -21,7 +25,9 @@ type tResource struct {
 type Resource struct {
 	Sources  []network.NetworkInfo
 	TimeSys  datetime.TimeSys
-	Interval int
+	Interval Interval
+	Checksum string        // template of the manifest url, e.g., "MD5SUMS" in the directory of the source
+	Latency  time.Duration // delay of publishing after the epoch of the file
 }
 
 /***** FUNCTION ********************************/
@@ -50,23 +56,59 @@ func ParseResourceJson(rsFile string, rsMap map[string]Resource) error {
 		var rs Resource
 
 		rs.TimeSys = datetime.ParseTimeSys(val.TimeSys)
+		rs.Checksum = val.Checksum
 
-		if val.Interval <= 0 {
-			return fmt.Errorf(`invalid "interval" of resource "%s"`, kw)
+		if rs.Interval, err = ParseInterval(val.Interval); err != nil {
+			return fmt.Errorf(`invalid "interval" of resource "%s", %s`, kw, err)
 		}
 
-		rs.Interval = val.Interval
+		if val.Latency != nil {
+			iv, err := ParseInterval(val.Latency)
+
+			if err != nil || iv.Unit != INTERVAL_SECOND {
+				return fmt.Errorf(`invalid "latency" of resource "%s", which must be in seconds, minutes, hours or days`, kw)
+			}
+
+			rs.Latency = time.Duration(iv.Num) * time.Second
+		}
 
 		for _, s := range val.Sources {
-			if !(s.IsFtp() || s.IsFtps() || s.IsHttp() || s.IsHttps() || s.IsHttpsCddis()) {
-				return fmt.Errorf(`unsupported url type for resource "%s"`, kw)
+			if err := s.ResolveCredentials(); err != nil {
+				return fmt.Errorf(`invalid credentials of resource "%s", %s`, kw, err)
+			}
+
+			if pURL, err := url.Parse(s.Url); err == nil && s.TLS != nil {
+				if err := network.RegisterTLSHost(pURL.Hostname(), s.TLS); err != nil {
+					return fmt.Errorf(`invalid "tls" of resource "%s", %s`, kw, err)
+				}
+			}
+
+			if _, err := network.GetDownloader(s.Url); err != nil {
+				return fmt.Errorf(`unsupported url type for resource "%s", %s`, kw, err)
+			}
+
+			switch s.FtpMode {
+			case "":
+				s.FtpMode = network.FTPModeAuto
+			case network.FTPModeAuto, network.FTPModePassive, network.FTPModeActive:
+			default:
+				return fmt.Errorf(`invalid "ftp mode" of resource "%s", which must be "%s", "%s" or "%s"`,
+					kw, network.FTPModeAuto, network.FTPModePassive, network.FTPModeActive)
+			}
+
+			// SFTP has no anonymous login, and the password is optional with a private key
+			if s.IsSftp() && s.UserName == "" {
+				return fmt.Errorf(`no "username" of the SFTP source for resource "%s"`, kw)
+			} else if s.IsSftp() && s.Password == "" && s.KeyFile == "" {
+				return fmt.Errorf(`no "password" or "private key" of the SFTP source for resource "%s"`, kw)
 			}
 
-			if s.UserName == "" {
+			// anonymous login of FTP/FTPS if no credentials are given or found
+			if (s.IsFtp() || s.IsFtps()) && s.UserName == "" {
 				s.UserName = "anonymous"
 			}
 
-			if s.Password == "" {
+			if (s.IsFtp() || s.IsFtps()) && s.Password == "" {
 				s.Password = "anonymous"
 			}
 
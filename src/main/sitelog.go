@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"godog/datetime"
+	"os"
+	"strings"
+)
+
+/***** STRUCT **********************************/
+
+// Receiver in the site log, valid from "date_installed" until "date_removed", which is empty if still installed.
+type LogReceiver struct {
+	Type      string `json:"receiver_type"`
+	Serial    string `json:"serial_number"`
+	Firmware  string `json:"firmware"`
+	Installed string `json:"date_installed"` // in UTC, e.g., "2023-07-06T00:00:00Z"
+	Removed   string `json:"date_removed"`
+}
+
+/***********************************************/
+
+// Antenna in the site log, valid from "date_installed" until "date_removed", which is empty if still installed.
+type LogAntenna struct {
+	Type      string     `json:"antenna_type"`
+	Serial    string     `json:"antenna_serial_number"`
+	UNE       [3]float64 `json:"antenna_marker_une"`
+	Installed string     `json:"date_installed"`
+	Removed   string     `json:"date_removed"`
+}
+
+/***********************************************/
+
+// History of the equipment of a site, which is parsed from its IGS site log by todog with "-IGS-site-logs".
+type SiteLog struct {
+	Name      string        `json:"name"`
+	XYZ       [3]float64    `json:"xyz"`
+	Receivers []LogReceiver `json:"receivers"`
+	Antennas  []LogAntenna  `json:"antennas"`
+}
+
+/***********************************************/
+
+type SiteLogArray struct {
+	Array []SiteLog `json:"data"`
+}
+
+/***** FUNCTION ********************************/
+
+// Check whether the epoch in the form of "2006-01-02T15:04:05Z" is in the interval [installed, removed),
+// the interval is open if the removal date is empty.
+func isInstalled(epoch, installed, removed string) bool {
+	return len(installed) != 0 && installed <= epoch && (len(removed) == 0 || epoch < removed)
+}
+
+/***** METHOD **********************************/
+
+func (a *SiteLogArray) parseJson(f string) error {
+	fp, err := os.Open(f)
+
+	if err != nil {
+		return fmt.Errorf("error occurs while parsing the json file, %s", err)
+	}
+
+	defer fp.Close()
+
+	dcr := json.NewDecoder(fp)
+
+	for dcr.More() {
+		err = dcr.Decode(a)
+
+		if err != nil {
+			return fmt.Errorf("error occurs while parsing the json file, %s", err)
+		}
+	}
+
+	if len(a.Array) == 0 {
+		return fmt.Errorf("empty file")
+	}
+
+	return nil
+}
+
+/***********************************************/
+
+// Get the site log of the target by its full name, the one of the 4-character name is also matched.
+func (a SiteLogArray) Get(name string) (SiteLog, bool) {
+	name = strings.ToUpper(name)
+
+	for _, e := range a.Array {
+		if e.Name == name || (len(e.Name) == 4 && strings.HasPrefix(name, e.Name)) {
+			return e, true
+		}
+	}
+
+	return SiteLog{}, false
+}
+
+/***********************************************/
+
+// Get the site information at the time, in which the receiver and antenna installed at that time replace the ones of info,
+// and false is returned if neither of them is found in the history.
+func (s SiteLog) At(t datetime.Time, info TargetInfo) (TargetInfo, bool) {
+	var found bool
+	epoch := t.ConvertNew(datetime.TIME_SYS_UTC).Format("{D}T{T}Z")
+
+	for _, rcv := range s.Receivers {
+		if isInstalled(epoch, rcv.Installed, rcv.Removed) {
+			info.RcvType, info.RcvSN, info.RcvFirm = rcv.Type, rcv.Serial, rcv.Firmware
+			found = true
+		}
+	}
+
+	for _, ant := range s.Antennas {
+		if isInstalled(epoch, ant.Installed, ant.Removed) {
+			info.AntType, info.AntSN, info.AntUNE = ant.Type, ant.Serial, ant.UNE
+			found = true
+		}
+	}
+
+	if found && s.XYZ != [3]float64{} {
+		info.XYZ = s.XYZ
+	}
+
+	return info, found
+}
+
+/***********************************************/
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"godog/network"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+/***** CONSTANT ********************************/
+
+const (
+	STATUS_PENDING = "pending" // the job is being done
+	STATUS_DONE    = "done"    // the file has been downloaded completely
+	STATUS_FAILED  = "failed"  // the job failed because of temporary errors, and will be retried
+	STATUS_MISSING = "missing" // the file is permanently missing in all sources
+
+	STATUS_UNAVAILABLE = "unavailable" // the file is not yet published within the latency of the resource
+)
+
+/***** STRUCT **********************************/
+
+// State of a job, which is stored in one line of the state file.
+type JobState struct {
+	Type    string `json:"type"`
+	Time    string `json:"time"`
+	Name    string `json:"name,omitempty"`
+	Path    string `json:"path"`
+	File    string `json:"file,omitempty"`    // path of the saved file, which may differ from Path if kept compressed
+	Url     string `json:"url,omitempty"`     // url of the source used or tried last
+	Product string `json:"product,omitempty"` // type of the resource used or tried last, if there are fallbacks
+	Status  string `json:"status"`
+	Index   int    `json:"source index"`
+	Size    int64  `json:"size"`
+	Sha256  string `json:"sha256,omitempty"`
+	Update  string `json:"update time"`
+
+	// size and modification time of the remote file, only recorded in the sync mode
+	RemoteSize int64  `json:"remote size,omitempty"`
+	RemoteTime string `json:"remote time,omitempty"`
+}
+
+/***********************************************/
+
+// A JSON-lines file storing the states of jobs, the latest line of a path takes effect.
+type StateDB struct {
+	mutex  sync.Mutex
+	path   string
+	fp     *os.File
+	states map[string]JobState // key: path of the job
+}
+
+/***** FUNCTION ********************************/
+
+// Open the state file, which is created if not exists, and compacted if exists.
+func OpenStateDB(path string) (*StateDB, error) {
+	db := &StateDB{path: path, states: make(map[string]JobState)}
+
+	// 1. load the existing states
+	if fp, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(fp)
+		var nl int
+
+		for scanner.Scan() {
+			nl++
+			var state JobState
+
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+
+			// the last line may be broken if the previous run was killed
+			if err = json.Unmarshal(scanner.Bytes(), &state); err != nil {
+				continue
+			}
+
+			db.states[state.Path] = state
+		}
+
+		err = scanner.Err()
+		fp.Close()
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the state file, line %d, %s", nl, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	// 2. compact the states into a temporary file, then replace the old one
+	fp, err := os.Create(path + ".tmp")
+
+	if err != nil {
+		return nil, err
+	}
+
+	writer := bufio.NewWriter(fp)
+	encoder := json.NewEncoder(writer)
+
+	paths := make([]string, 0, len(db.states))
+
+	for p := range db.states {
+		paths = append(paths, p)
+	}
+
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		if err = encoder.Encode(db.states[p]); err != nil {
+			break
+		}
+	}
+
+	if err == nil {
+		err = writer.Flush()
+	}
+
+	fp.Close()
+
+	if err == nil {
+		err = os.Rename(path+".tmp", path)
+	}
+
+	if err != nil {
+		os.Remove(path + ".tmp")
+		return nil, fmt.Errorf("failed to compact the state file, %s", err)
+	}
+
+	// 3. open it for appending
+	db.fp, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0664)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+/***********************************************/
+
+// Get the size and the sha256 checksum of a file.
+func FileSum(path string) (size int64, sum string, err error) {
+	fp, err := os.Open(path)
+
+	if err != nil {
+		return 0, "", err
+	}
+
+	defer fp.Close()
+
+	h := sha256.New()
+
+	if size, err = io.Copy(h, fp); err != nil {
+		return 0, "", err
+	}
+
+	return size, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+/***** METHOD **********************************/
+
+func (db *StateDB) Close() error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	return db.fp.Close()
+}
+
+/***********************************************/
+
+func (db *StateDB) Get(path string) (JobState, bool) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	state, ok := db.states[path]
+	return state, ok
+}
+
+/***********************************************/
+
+// Record the state of the job, it is written into the file immediately.
+func (db *StateDB) Put(job *Job, status string) error {
+	state := JobState{
+		Type:    job.Type,
+		Time:    job.Epoch(),
+		Name:    job.Name,
+		Path:    job.Path,
+		Status:  status,
+		Index:   job.Index,
+		Url:     network.Redact(job.Url),
+		Product: job.Product,
+		Update:  time.Now().UTC().Format(time.RFC3339),
+	}
+
+	if status == STATUS_DONE {
+		state.File, state.Size, state.Sha256 = job.File, job.Size, job.Sum
+
+		if job.Remote.Size > 0 {
+			state.RemoteSize = job.Remote.Size
+		}
+
+		if !job.Remote.ModTime.IsZero() {
+			state.RemoteTime = job.Remote.ModTime.UTC().Format(time.RFC3339)
+		}
+	}
+
+	bs, err := json.Marshal(state)
+
+	if err != nil {
+		return err
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	db.states[state.Path] = state
+
+	if _, err = db.fp.Write(append(bs, '\n')); err != nil {
+		return err
+	}
+
+	return db.fp.Sync()
+}
+
+/***********************************************/
+
+// Check whether the job has been done in a previous run, the saved file is verified by its size and checksum.
+func (db *StateDB) IsDone(path string) bool {
+	state, ok := db.Get(path)
+
+	if !ok || state.Status != STATUS_DONE {
+		return false
+	}
+
+	size, sum, err := FileSum(state.File)
+
+	return err == nil && size == state.Size && sum == state.Sha256
+}
+
+/***********************************************/
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"godog/network"
+	"os"
+	"time"
+)
+
+/***** FUNCTION ********************************/
+
+// Get the size and modification time of the remote file with the downloader matching the url of the source.
+func stat(source network.NetworkInfo) (network.FileInfo, network.TaskError) {
+	d, err := network.GetDownloader(source.Url)
+
+	if err != nil {
+		return network.FileInfo{Size: -1}, network.NewTaskError(err, false)
+	}
+
+	return d.Stat(&source)
+}
+
+/***********************************************/
+
+// Check whether the existing file is up to date in the sync mode. The remote file used last time is compared with the size
+// and modification time recorded in the state, or with the modification time of the local file if not recorded.
+// If the remote file is not found, the local one is kept, and if the remote one cannot be checked, it is downloaded again.
+func isUpToDate(job *Job) bool {
+	var (
+		sources  = rsMap[job.Type].Sources
+		file     = job.Path
+		index    = 1
+		rawURL   string
+		lastTime time.Time
+		lastSize int64
+	)
+
+	if state, ok := stateDB.Get(job.Path); ok && state.Status == STATUS_DONE && len(state.Url) != 0 {
+		file, index, rawURL, lastSize = state.File, state.Index, state.Url, state.RemoteSize
+		job.Product = state.Product
+		lastTime, _ = time.Parse(time.RFC3339, state.RemoteTime)
+	}
+
+	if len(job.Product) != 0 {
+		sources = rsMap[job.Product].Sources
+	}
+
+	info, err := os.Stat(file)
+
+	if err != nil || index < 1 || index > len(sources) {
+		return false
+	}
+
+	if lastTime.IsZero() {
+		lastTime = info.ModTime()
+	}
+
+	source := sources[index-1]
+
+	if len(rawURL) == 0 {
+		urls, err := matchURL(job, source)
+
+		if err != nil {
+			return network.IsNotFound(err)
+		}
+
+		rawURL = urls[0]
+	}
+
+	source.Url = rawURL
+	remote, tErr := stat(source)
+
+	if tErr != nil {
+		return network.IsNotFound(tErr)
+	}
+
+	if remote.Size >= 0 && lastSize > 0 && remote.Size != lastSize {
+		return false
+	}
+
+	return remote.ModTime.IsZero() || !remote.ModTime.After(lastTime)
+}
+
+/***********************************************/
@@ -2,16 +2,53 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"os"
+	"path"
 	"slices"
+	"sort"
 	"strings"
 )
 
+/***** CONSTANT ********************************/
+
+const (
+	EARTH_RADIUS       = 6371.0 // mean radius of the Earth in km
+	WGS84_A            = 6378137.0
+	WGS84_F            = 1 / 298.257223563
+	SITE_STATUS_ACTIVE = 4 // status of the active stations in the IGS site information
+)
+
 /***** STRUCT **********************************/
 
 type TargetInfo struct {
-	Name string `json:"name"`
+	Name    string     `json:"name"`
+	Status  int8       `json:"status"`
+	XYZ     [3]float64 `json:"xyz"` // ECEF coordinates in meters
+	LLH     [3]float64 `json:"llh"` // latitude and longitude in degrees, height in meters
+	System  []string   `json:"satellite_system"`
+	AntType string     `json:"antenna_type"`
+	AntSN   string     `json:"antenna_serial_number"`
+	AntUNE  [3]float64 `json:"antenna_marker_une"`
+	RcvType string     `json:"receiver_type"`
+	RcvSN   string     `json:"serial_number"`
+	RcvFirm string     `json:"firmware"`
+}
+
+/***** STRUCT **********************************/
+
+// Selectors of targets from the information file, in which all the specified ones must be satisfied.
+type TargetSelector struct {
+	Status   []int8    `json:"status"`   // e.g., [4] for the active stations
+	Near     []float64 `json:"near"`     // latitude and longitude of the point in degrees
+	Radius   float64   `json:"radius"`   // maximum distance to "near" in km
+	Num      int       `json:"num"`      // number of the nearest targets to "near"
+	Box      []float64 `json:"box"`      // [min latitude, min longitude, max latitude, max longitude] in degrees
+	Systems  []string  `json:"systems"`  // satellite systems all tracked, e.g., ["BDS", "GAL"]
+	Receiver string    `json:"receiver"` // pattern of the receiver type, e.g., "SEPT*"
+	Antenna  string    `json:"antenna"`  // pattern of the antenna type
 }
 
 /***** STRUCT **********************************/
@@ -20,28 +57,247 @@ type TargetInfoArray struct {
 	Array []TargetInfo `json:"data"`
 }
 
+/***** FUNCTION ********************************/
+
+// Get the great-circle distance in km between two points given by latitudes and longitudes in degrees.
+func distance(lat1, lon1, lat2, lon2 float64) float64 {
+	lat1, lon1 = lat1*math.Pi/180, lon1*math.Pi/180
+	lat2, lon2 = lat2*math.Pi/180, lon2*math.Pi/180
+
+	h := math.Pow(math.Sin((lat2-lat1)/2), 2) + math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin((lon2-lon1)/2), 2)
+	return 2 * EARTH_RADIUS * math.Asin(math.Sqrt(min(h, 1)))
+}
+
 /***** METHOD **********************************/
 
-func (a TargetInfoArray) Contains(name string) bool {
-	name = strings.ToUpper(name)
+// Get the geodetic latitude and longitude in degrees, which are computed from the coordinates if given,
+// since the latitudes and longitudes in the IGS site information are wrong for a few stations.
+func (e TargetInfo) LatLon() (float64, float64) {
+	x, y, z := e.XYZ[0], e.XYZ[1], e.XYZ[2]
+	p := math.Hypot(x, y)
+
+	if p == 0 {
+		return e.LLH[0], e.LLH[1]
+	}
+
+	e2 := WGS84_F * (2 - WGS84_F)
+	lat := math.Atan2(z, p*(1-e2))
 
-	caller := func(e TargetInfo) bool {
-		return strings.Contains(e.Name, name)
+	for i := 0; i < 5; i++ {
+		sinLat := math.Sin(lat)
+		n := WGS84_A / math.Sqrt(1-e2*sinLat*sinLat)
+		lat = math.Atan2(z+e2*n*sinLat, p)
 	}
 
-	return slices.ContainsFunc(a.Array, caller)
+	return lat * 180 / math.Pi, math.Atan2(y, x) * 180 / math.Pi
 }
 
 /***********************************************/
 
-func (a TargetInfoArray) Index(name string) int {
+// Check whether the selectors are valid.
+func (s *TargetSelector) Check() error {
+	if len(s.Near) != 0 && (len(s.Near) != 2 || math.Abs(s.Near[0]) > 90) {
+		return fmt.Errorf(`invalid "near", which must be [latitude, longitude] in degrees`)
+	}
+
+	if s.Radius < 0 || (s.Radius > 0 && len(s.Near) == 0) {
+		return fmt.Errorf(`invalid "radius", which must be positive with "near"`)
+	}
+
+	if s.Num < 0 || (s.Num > 0 && len(s.Near) == 0) {
+		return fmt.Errorf(`invalid "num", which must be positive with "near"`)
+	}
+
+	if len(s.Box) != 0 && (len(s.Box) != 4 || s.Box[0] > s.Box[2] || math.Abs(s.Box[0]) > 90 || math.Abs(s.Box[2]) > 90) {
+		return fmt.Errorf(`invalid "box", which must be [min latitude, min longitude, max latitude, max longitude] in degrees`)
+	}
+
+	for _, pattern := range []string{s.Receiver, s.Antenna} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf(`invalid pattern "%s" of "receiver" or "antenna"`, pattern)
+		}
+	}
+
+	return nil
+}
+
+/***********************************************/
+
+// Check whether the target satisfies the selectors except "num".
+func (s *TargetSelector) Match(e TargetInfo) bool {
+	lat, lon := e.LatLon()
+
+	if len(s.Status) != 0 && !slices.Contains(s.Status, e.Status) {
+		return false
+	}
+
+	if s.Radius > 0 && distance(s.Near[0], s.Near[1], lat, lon) > s.Radius {
+		return false
+	}
+
+	if len(s.Box) != 0 {
+		if lat < s.Box[0] || lat > s.Box[2] {
+			return false
+		}
+
+		// the box may cross the antimeridian, e.g., [-50, 170, -30, -170], or cover all longitudes if the span is 360
+		span := s.Box[3] - s.Box[1]
+
+		if span < 0 {
+			span += 360
+		}
+
+		if span < 360 && math.Mod(lon-s.Box[1]+720, 360) > span {
+			return false
+		}
+	}
+
+	for _, sys := range s.Systems {
+		if !slices.ContainsFunc(e.System, func(x string) bool { return strings.EqualFold(x, sys) }) {
+			return false
+		}
+	}
+
+	if ok, _ := path.Match(strings.ToUpper(s.Receiver), strings.ToUpper(e.RcvType)); len(s.Receiver) != 0 && !ok {
+		return false
+	}
+
+	if ok, _ := path.Match(strings.ToUpper(s.Antenna), strings.ToUpper(e.AntType)); len(s.Antenna) != 0 && !ok {
+		return false
+	}
+
+	return true
+}
+
+/***********************************************/
+
+// Get the names of the targets satisfying the selectors, and only the nearest ones are kept if "num" is specified.
+func (a TargetInfoArray) Select(s *TargetSelector) []string {
+	var selected []TargetInfo
+
+	for _, e := range a.Array {
+		if s.Match(e) {
+			selected = append(selected, e)
+		}
+	}
+
+	if s.Num > 0 {
+		dist := func(e TargetInfo) float64 {
+			lat, lon := e.LatLon()
+			return distance(s.Near[0], s.Near[1], lat, lon)
+		}
+
+		sort.SliceStable(selected, func(i, j int) bool {
+			return dist(selected[i]) < dist(selected[j])
+		})
+
+		selected = selected[:min(s.Num, len(selected))]
+	}
+
+	names := make([]string, len(selected))
+
+	for i, e := range selected {
+		names[i] = e.Name
+	}
+
+	return names
+}
+
+/***********************************************/
+
+// Find the names of the targets matching the given one, which is case-insensitive and one of
+//   - the 9-character long name, e.g., "WUHN00CHN";
+//   - the 4-character short name, e.g., "WUHN", or with the monument and receiver numbers, e.g., "WUH200";
+//   - the short name with the country code, e.g., "WUHN:CHN";
+//   - a pattern with "*", "?" or "[", e.g., "WTZ?00DEU" or "WUH*", which may match several targets.
+//
+// It returns no names if not found, and an error listing the candidates if the name is ambiguous.
+func (a TargetInfoArray) Find(name string) ([]string, error) {
+	var (
+		names []string
+		match func(e string) bool
+	)
+
 	name = strings.ToUpper(name)
+	short, country, hasCountry := strings.Cut(name, ":")
+	isPattern := strings.ContainsAny(name, "*?[")
+
+	switch {
+	case isPattern:
+		if _, err := path.Match(name, ""); err != nil {
+			return nil, fmt.Errorf(`invalid pattern "%s"`, name)
+		}
+
+		match = func(e string) bool { ok, _ := path.Match(name, e); return ok }
+	case len(name) == 9:
+		match = func(e string) bool { return e == name }
+	case hasCountry && len(short) == 4 && len(country) == 3:
+		match = func(e string) bool { return len(e) == 9 && e[:4] == short && e[6:] == country }
+	case !hasCountry && (len(name) == 4 || len(name) == 6):
+		match = func(e string) bool { return strings.HasPrefix(e, name) }
+	default:
+		return nil, fmt.Errorf(`invalid name "%s", which must be "XXXX", "XXXXMR", "XXXXMRCCC", "XXXX:CCC" or a pattern`, name)
+	}
+
+	for _, e := range a.Array {
+		if match(strings.ToUpper(e.Name)) {
+			names = append(names, e.Name)
+		}
+	}
+
+	if len(names) > 1 && !isPattern {
+		return nil, fmt.Errorf(`ambiguous name "%s", candidates: %s`, name, strings.Join(names, ", "))
+	}
+
+	return names, nil
+}
+
+/***********************************************/
+
+// Get the information of the target by its full name.
+func (a TargetInfoArray) Get(name string) (TargetInfo, bool) {
+	if i := slices.IndexFunc(a.Array, func(e TargetInfo) bool { return e.Name == name }); i >= 0 {
+		return a.Array[i], true
+	}
+
+	return TargetInfo{}, false
+}
+
+/***********************************************/
+
+// Get the names of the targets, in which the names not found are returned separately,
+// and all the invalid or ambiguous names are reported in the error.
+func (a TargetInfoArray) Resolve(targets []string) ([]string, []string, error) {
+	var (
+		names    = make([]string, 0, len(targets))
+		notFound []string
+		errs     []string
+	)
+
+	for _, target := range targets {
+		found, err := a.Find(target)
+
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+
+		if len(found) == 0 {
+			notFound = append(notFound, target)
+		}
+
+		for _, name := range found {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
 
-	caller := func(e TargetInfo) bool {
-		return strings.Contains(e.Name, name)
+	if len(errs) != 0 {
+		return nil, nil, errors.New(strings.Join(errs, "; "))
 	}
 
-	return slices.IndexFunc(a.Array, caller)
+	return names, notFound, nil
 }
 
 /***********************************************/
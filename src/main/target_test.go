@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestTargetSelectorBox(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		box  []float64
+		lat  float64
+		lon  float64
+		want bool
+	}{
+		{"inside", []float64{30, 0, 60, 20}, 45, 10, true},
+		{"east of", []float64{30, 0, 60, 20}, 45, 25, false},
+		{"south of", []float64{30, 0, 60, 20}, 20, 10, false},
+		{"antimeridian west side", []float64{-50, 170, -30, -170}, -40, 175, true},
+		{"antimeridian east side", []float64{-50, 170, -30, -170}, -40, -175, true},
+		{"antimeridian on the line", []float64{-50, 170, -30, -170}, -40, 180, true},
+		{"antimeridian outside", []float64{-50, 170, -30, -170}, -40, 0, false},
+		{"antimeridian west of", []float64{-50, 170, -30, -170}, -40, 160, false},
+		{"full longitude", []float64{30, -180, 60, 180}, 45, 0, true},
+		{"full longitude west end", []float64{30, -180, 60, 180}, 45, -179.5, true},
+		{"full longitude east end", []float64{30, -180, 60, 180}, 45, 179.5, true},
+		{"full longitude shifted", []float64{30, 0, 60, 360}, 45, -90, true},
+		{"full longitude south of", []float64{30, -180, 60, 180}, 20, 0, false},
+	} {
+		s := TargetSelector{Box: tc.box}
+
+		if err := s.Check(); err != nil {
+			t.Fatalf("%s: %s", tc.name, err)
+		}
+
+		if got := s.Match(TargetInfo{LLH: [3]float64{tc.lat, tc.lon, 0}}); got != tc.want {
+			t.Errorf("%s: Match(%g, %g) in box %v = %v, want %v", tc.name, tc.lat, tc.lon, tc.box, got, tc.want)
+		}
+	}
+}
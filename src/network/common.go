@@ -1,9 +1,13 @@
 package network
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"net/url"
+	"os"
 	"strings"
+	"time"
 )
 
 /***** CONSTANT ********************************/
@@ -55,6 +59,7 @@ func SizeRepr(size int64) string {
 /***** CONSTANT ********************************/
 
 const (
+	FTPCodePositivePreliminary  = 1
 	FTPCodePositive             = 2
 	FTPCodeFileStatusOk         = 150 // about to open data connection
 	FTPCodeCommandOk            = 200
@@ -62,6 +67,7 @@ const (
 	FTPCodeFileStatus           = 213
 	FTPCodeServiceReady         = 220
 	FTPCodePassiveMode          = 227
+	FTPCodeExtendedPassiveMode  = 229
 	FTPCodeLoggedIn             = 230
 	FTPCodeAuthOk               = 234
 	FTPCodeFileActionOk         = 250
@@ -69,9 +75,15 @@ const (
 	FTPCodeFileActionPending    = 350 // pending further information
 	FTPCodeDataConnectionFailed = 425
 	FTPCodeConnectionClosed     = 426
+	FTPCodeFileUnavailable      = 550
 	HTTPUserAgent               = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_12_6) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/12.0.3 Safari/605.1.15"
 )
 
+/***** VARIABLE ********************************/
+
+// error indicating that the file does not exist on the server
+var ErrNotFound = errors.New("file not found")
+
 /***** STRUCT **********************************/
 
 type TaskError interface {
@@ -111,52 +123,118 @@ func (e taskError) IsEOF() bool {
 	return e.err == io.EOF
 }
 
+/***********************************************/
+
+// Check whether the error is caused by a missing file on the server.
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+/***********************************************/
+
+func (e taskError) Unwrap() error {
+	return e.err
+}
+
 /***** STRUCT **********************************/
 
 type NetworkInfo struct {
 	Url      string `json:"url"`
 	UserName string `json:"username"`
 	Password string `json:"password"`
+	Regex    bool   `json:"regex"` // whether the file name in the url is a regular expression instead of a glob pattern
+	Token    string `json:"token"` // bearer token, e.g., the one of NASA Earthdata login, which is used instead of the username and password
+
+	// command to get the credentials if no password or token, e.g., "pass show cddis", see ResolveCredentials
+	CredentialCmd string `json:"credential command"`
+
+	// options of FTP/FTPS
+	FtpMode        string `json:"ftp mode"`         // mode of data connections, "auto" (default), "passive" or "active"
+	IgnorePasvHost bool   `json:"ignore pasv host"` // whether to use the host of the control connection instead of the one in the reply of PASV
+	ImplicitFtps   bool   `json:"implicit ftps"`    // whether TLS is from the beginning instead of "AUTH TLS" for FTPS, true if on port 990
+
+	// options of TLS for FTPS and HTTPS, which are shared by all sources of the host, optional
+	TLS *TLSConfig `json:"tls"`
+
+	// options of SFTP
+	KeyFile       string `json:"private key"`            // path of the private key, optional
+	KeyPassphrase string `json:"private key passphrase"` // passphrase of the encrypted private key, optional
+	KnownHosts    string `json:"known hosts"`            // path of the known_hosts file to verify the host key, "~/.ssh/known_hosts" by default
 }
 
 /***** FUNCTION ********************************/
 
-func (s *NetworkInfo) IsHttp() bool {
-	return strings.Contains(s.Url, "http://")
-}
+// Get the scheme and host of the url in lower case.
+func (s *NetworkInfo) schemeHost() (string, string) {
+	pURL, err := url.Parse(s.Url)
 
-/***********************************************/
+	if err != nil {
+		return "", ""
+	}
 
-func (s *NetworkInfo) IsHttps() bool {
-	return strings.Contains(s.Url, "https://") &&
-		(!strings.Contains(s.Url, "https://cddis"))
+	return strings.ToLower(pURL.Scheme), strings.ToLower(pURL.Hostname())
 }
 
 /***********************************************/
 
-func (s *NetworkInfo) IsHttpsCddis() bool {
-	return strings.Contains(s.Url, "https://cddis")
+func (s *NetworkInfo) IsFtp() bool {
+	scheme, _ := s.schemeHost()
+	return scheme == "ftp"
 }
 
 /***********************************************/
 
-func (s *NetworkInfo) IsFtp() bool {
-	return strings.Contains(s.Url, "ftp://")
+func (s *NetworkInfo) IsFtps() bool {
+	scheme, _ := s.schemeHost()
+	return scheme == "ftps"
 }
 
 /***********************************************/
 
-func (s *NetworkInfo) IsFtps() bool {
-	return strings.Contains(s.Url, "ftps://")
+func (s *NetworkInfo) IsSftp() bool {
+	scheme, _ := s.schemeHost()
+	return scheme == "sftp"
 }
 
 /***** STRUCT **********************************/
 
+// Size and modification time of a remote file.
+type FileInfo struct {
+	Size    int64     // -1 if unknown
+	ModTime time.Time // zero if unknown
+}
+
+/***********************************************/
+
 type NetworkTask struct {
 	Source   NetworkInfo // URL, username and password
 	Path     string      // path of the file to be saved
 	Size     int64       // size of downloaded part
 	Continue bool        // whether to resume getting a partially-downloaded file or not
+	Writer   io.Writer   // if not nil, the data are written into it instead of the file, and resuming is disabled
+}
+
+/***********************************************/
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+/***** FUNCTION ********************************/
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+/***********************************************/
+
+// Open the destination of the task, i.e., the writer if given, otherwise the file to be saved.
+func (f *NetworkTask) open(flag int) (io.WriteCloser, error) {
+	if f.Writer != nil {
+		return nopWriteCloser{f.Writer}, nil
+	}
+
+	return os.OpenFile(f.Path, flag, 0664)
 }
 
 /***********************************************/
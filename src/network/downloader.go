@@ -0,0 +1,161 @@
+package network
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+/***** STRUCT **********************************/
+
+// Backend of a protocol or an archive, which lists, stats and downloads the remote files.
+type Downloader interface {
+	List(s *NetworkInfo) ([]string, TaskError)
+	Stat(s *NetworkInfo) (FileInfo, TaskError)
+	Download(f *NetworkTask) TaskError
+}
+
+/***********************************************/
+
+// Rule to choose the downloader of a url by its scheme and host.
+type downloaderRule struct {
+	scheme     string
+	host       string // exact host name, or suffix beginning with ".", or empty for any host
+	downloader Downloader
+}
+
+/***** VARIABLE ********************************/
+
+var downloaders struct {
+	mutex sync.RWMutex
+	rules []downloaderRule
+}
+
+/***** FUNCTION ********************************/
+
+func init() {
+	RegisterDownloader("ftp", "", ftpDownloader{})
+	RegisterDownloader("ftps", "", ftpsDownloader{})
+	RegisterDownloader("sftp", "", sftpDownloader{})
+	RegisterDownloader("http", "", httpDownloader{})
+	RegisterDownloader("https", "", httpDownloader{})
+
+	// archives protected by NASA Earthdata login, e.g., PO.DAAC, LAADS and ASF
+	RegisterEarthdataHost(".earthdata.nasa.gov")
+	RegisterEarthdataHost(".eosdis.nasa.gov")
+	RegisterEarthdataHost("datapool.asf.alaska.edu")
+	RegisterDownloader("https", "cddis.nasa.gov", earthdataDownloader{cddis: true})
+	RegisterDownloader("https", "cddis.gsfc.nasa.gov", earthdataDownloader{cddis: true})
+}
+
+/***********************************************/
+
+// Register the downloader for the urls with the scheme and host. The host is an exact name, e.g., "cddis.nasa.gov",
+// or a suffix beginning with ".", e.g., ".nasa.gov", or empty for any host. The exact hosts have priority over the suffixes,
+// which have priority over the empty one, and the rule registered later has priority over the earlier one of the same kind.
+func RegisterDownloader(scheme, host string, d Downloader) {
+	downloaders.mutex.Lock()
+	defer downloaders.mutex.Unlock()
+
+	downloaders.rules = append(downloaders.rules, downloaderRule{
+		scheme:     strings.ToLower(scheme),
+		host:       strings.ToLower(host),
+		downloader: d,
+	})
+}
+
+/***********************************************/
+
+// Get the downloader for the url by its scheme and host.
+func GetDownloader(rawURL string) (Downloader, error) {
+	pURL, err := url.Parse(rawURL)
+
+	if err != nil {
+		return nil, fmt.Errorf(`invalid url "%s", %s`, rawURL, err)
+	}
+
+	scheme, host := strings.ToLower(pURL.Scheme), strings.ToLower(pURL.Hostname())
+
+	downloaders.mutex.RLock()
+	defer downloaders.mutex.RUnlock()
+
+	var (
+		found Downloader
+		level = -1 // 0 for any host, 1 for a suffix, 2 for an exact host
+	)
+
+	for _, rule := range downloaders.rules {
+		if rule.scheme != scheme {
+			continue
+		}
+
+		l := -1
+
+		switch {
+		case rule.host == "":
+			l = 0
+		case strings.HasPrefix(rule.host, ".") && strings.HasSuffix(host, rule.host):
+			l = 1
+		case rule.host == host:
+			l = 2
+		}
+
+		if l >= level && l >= 0 {
+			found, level = rule.downloader, l
+		}
+	}
+
+	if found == nil {
+		return nil, fmt.Errorf(`unsupported protocol of "%s"`, rawURL)
+	}
+
+	return found, nil
+}
+
+/***** STRUCT **********************************/
+
+// Downloaders of the built-in protocols and archives.
+type (
+	ftpDownloader       struct{}
+	ftpsDownloader      struct{}
+	sftpDownloader      struct{}
+	httpDownloader      struct{}
+	earthdataDownloader struct {
+		cddis bool // whether to list via the "*?list" endpoint of CDDIS
+	}
+)
+
+/***** METHOD **********************************/
+
+func (ftpDownloader) List(s *NetworkInfo) ([]string, TaskError) { return FTPList(s) }
+func (ftpDownloader) Stat(s *NetworkInfo) (FileInfo, TaskError) { return FTPStat(s) }
+func (ftpDownloader) Download(f *NetworkTask) TaskError         { return FTPDownload(f) }
+
+/***********************************************/
+
+func (ftpsDownloader) List(s *NetworkInfo) ([]string, TaskError) { return FTPSList(s) }
+func (ftpsDownloader) Stat(s *NetworkInfo) (FileInfo, TaskError) { return FTPSStat(s) }
+func (ftpsDownloader) Download(f *NetworkTask) TaskError         { return FTPSDownload(f) }
+
+/***********************************************/
+
+func (sftpDownloader) List(s *NetworkInfo) ([]string, TaskError) { return SFTPList(s) }
+func (sftpDownloader) Stat(s *NetworkInfo) (FileInfo, TaskError) { return SFTPStat(s) }
+func (sftpDownloader) Download(f *NetworkTask) TaskError         { return SFTPDownload(f) }
+
+/***********************************************/
+
+func (httpDownloader) List(s *NetworkInfo) ([]string, TaskError) { return HTTPList(s) }
+func (httpDownloader) Stat(s *NetworkInfo) (FileInfo, TaskError) { return HTTPStat(s) }
+func (httpDownloader) Download(f *NetworkTask) TaskError         { return HTTPDownload(f) }
+
+/***********************************************/
+
+func (d earthdataDownloader) List(s *NetworkInfo) ([]string, TaskError) {
+	return EarthdataList(s, d.cddis)
+}
+func (earthdataDownloader) Stat(s *NetworkInfo) (FileInfo, TaskError) { return EarthdataStat(s) }
+func (earthdataDownloader) Download(f *NetworkTask) TaskError         { return EarthdataDownload(f) }
+
+/***********************************************/
@@ -0,0 +1,409 @@
+package network
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/***** VARIABLE ********************************/
+
+// hosts of NASA Earthdata login, to which the credentials are sent in the redirect chain of OAuth
+var earthdataLoginHosts = []string{"urs.earthdata.nasa.gov", "uat.urs.earthdata.nasa.gov"}
+
+/***** STRUCT **********************************/
+
+// Session of Earthdata login for one user, the cookies of the archives are kept in the jar after the OAuth redirect chain,
+// so that the following requests to the archives are not redirected again until the cookies expire.
+type earthdataSession struct {
+	jar      *cookiejar.Jar
+	mutex    sync.Mutex // only one request of the session logs in at a time
+	username string
+	password string
+	token    string // EDL bearer token, which is used instead of the username and password if not empty
+}
+
+/***** VARIABLE ********************************/
+
+var earthdataSessions = struct {
+	mutex    sync.Mutex
+	sessions map[string]*earthdataSession // key: username or hash of the token
+}{sessions: make(map[string]*earthdataSession)}
+
+/***** FUNCTION ********************************/
+
+// Register the host of an archive protected by Earthdata login, e.g., "e4ftl01.cr.usgs.gov", or a suffix beginning with ".".
+func RegisterEarthdataHost(host string) {
+	RegisterDownloader("https", host, earthdataDownloader{})
+}
+
+/***********************************************/
+
+// Get the session of the user, the credentials are the token, or the username and password, or the entry of Earthdata login in .netrc.
+func getEarthdataSession(s *NetworkInfo) (*earthdataSession, TaskError) {
+	var key string
+	session := earthdataSession{username: s.UserName, password: s.Password, token: s.Token}
+
+	if len(session.token) != 0 {
+		sum := sha256.Sum256([]byte(session.token))
+		key = "token:" + hex.EncodeToString(sum[:8])
+	} else {
+		if len(session.password) == 0 {
+			for _, host := range earthdataLoginHosts {
+				if entry, ok := LookupNetrc(host, session.username); ok {
+					session.username, session.password = entry.Login, entry.Password
+					break
+				}
+			}
+		}
+
+		if len(session.username) == 0 || len(session.password) == 0 {
+			err := fmt.Errorf("no credentials of Earthdata login, which are the token, the username and password, or the entry of %s in .netrc",
+				earthdataLoginHosts[0])
+			return nil, taskError{err: err, flag: false}
+		}
+
+		key = "user:" + session.username
+	}
+
+	earthdataSessions.mutex.Lock()
+	defer earthdataSessions.mutex.Unlock()
+
+	if e, ok := earthdataSessions.sessions[key]; ok {
+		return e, nil
+	}
+
+	session.jar, _ = cookiejar.New(nil)
+	earthdataSessions.sessions[key] = &session
+	return &session, nil
+}
+
+/***** METHOD **********************************/
+
+// Get the client sharing the cookie jar of the session, with the TLS settings of the hosts, no timeout if timeout is zero.
+func (e *earthdataSession) client(s *NetworkInfo, timeout time.Duration) (*http.Client, TaskError) {
+	client, tErr := httpClient(s, timeout)
+
+	if tErr != nil {
+		return nil, tErr
+	}
+
+	client.Jar, client.CheckRedirect = e.jar, e.checkRedirect
+	return client, nil
+}
+
+/***********************************************/
+
+// Add the credentials to the redirected request to Earthdata login, or the token to the one to the archive,
+// since the "Authorization" header is removed by the client when redirected to another host.
+func (e *earthdataSession) checkRedirect(request *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+
+	host := strings.ToLower(request.URL.Hostname())
+	isLogin := slices.Contains(earthdataLoginHosts, host)
+
+	// the credentials are never sent in plain text
+	if request.URL.Scheme != "https" {
+		return nil
+	}
+
+	if len(e.token) != 0 && (isLogin || host == strings.ToLower(via[0].URL.Hostname())) {
+		request.Header.Set("Authorization", "Bearer "+e.token)
+	} else if len(e.token) == 0 && isLogin {
+		request.SetBasicAuth(e.username, e.password)
+	}
+
+	return nil
+}
+
+/***********************************************/
+
+// Create the request with the token if any.
+func (e *earthdataSession) newRequest(ctx context.Context, method, rawURL string) (*http.Request, error) {
+	request, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("User-Agent", HTTPUserAgent)
+
+	if len(e.token) != 0 {
+		request.Header.Set("Authorization", "Bearer "+e.token)
+	}
+
+	return request, nil
+}
+
+/***********************************************/
+
+// Send the request, and the request without cookies of the archive is sent alone,
+// so that the others wait for its login and reuse the cookies instead of logging in again.
+func (e *earthdataSession) do(s *NetworkInfo, timeout time.Duration, request *http.Request) (*http.Response, TaskError) {
+	client, tErr := e.client(s, timeout)
+
+	if tErr != nil {
+		return nil, tErr
+	}
+
+	if len(e.token) == 0 && len(e.jar.Cookies(request.URL)) == 0 {
+		e.mutex.Lock()
+		defer e.mutex.Unlock()
+	}
+
+	response, err := client.Do(request)
+
+	if err != nil {
+		return nil, taskError{err: err, flag: !isCertError(err)}
+	}
+
+	return response, nil
+}
+
+/***** FUNCTION ********************************/
+
+// Check the status of the final response in the redirect chain, which stays at Earthdata login if the credentials are rejected.
+func earthdataStatus(response *http.Response) TaskError {
+	host := strings.ToLower(response.Request.URL.Hostname())
+
+	switch code := response.StatusCode; {
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		err := fmt.Errorf("rejected by Earthdata login, response status code %d", code)
+		return taskError{err: err, flag: false}
+	case slices.Contains(earthdataLoginHosts, host):
+		err := fmt.Errorf("failed to log in to Earthdata, please check the credentials and whether the archive is authorized in the profile, response status code %d", code)
+		return taskError{err: err, flag: false}
+	case code == http.StatusNotFound || code == http.StatusGone:
+		err := fmt.Errorf("%w, response status code %d", ErrNotFound, code)
+		return taskError{err: err, flag: false}
+	case code != http.StatusOK && code != http.StatusPartialContent:
+		err := fmt.Errorf("invalid response status code %d", code)
+		return taskError{err: err, flag: true}
+	}
+
+	return nil
+}
+
+/***********************************************/
+
+// List the names of files in the directory, via the "*?list" endpoint of CDDIS which gives lines of "<name> <size>",
+// or the links in the index page of the other archives.
+func EarthdataList(s *NetworkInfo, cddis bool) ([]string, TaskError) {
+	e, tErr := getEarthdataSession(s)
+
+	if tErr != nil {
+		return nil, tErr
+	}
+
+	defer getURLLimiter(s.Url).acquire()()
+
+	rawURL := s.Url
+
+	if cddis {
+		rawURL = strings.TrimSuffix(s.Url, "/") + "/*?list"
+	}
+
+	request, err := e.newRequest(context.TODO(), http.MethodGet, rawURL)
+
+	if err != nil {
+		return nil, taskError{err: err, flag: false}
+	}
+
+	response, tErr := e.do(s, time.Minute, request)
+
+	if tErr != nil {
+		return nil, tErr
+	}
+
+	defer response.Body.Close()
+
+	if tErr = earthdataStatus(response); tErr != nil {
+		return nil, tErr
+	}
+
+	if !cddis {
+		body, err := io.ReadAll(response.Body)
+
+		if err != nil {
+			return nil, taskError{err: err, flag: true}
+		}
+
+		return parseIndexLinks(body), nil
+	}
+
+	var names []string
+	scanner := bufio.NewScanner(response.Body)
+
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) != 0 && !strings.HasPrefix(fields[0], "#") {
+			names = append(names, fields[0])
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, taskError{err: err, flag: true}
+	}
+
+	return names, nil
+}
+
+/***********************************************/
+
+// Get the size and modification time of the file by getting its first byte, since HEAD may be not allowed in the redirect chain.
+func EarthdataStat(s *NetworkInfo) (FileInfo, TaskError) {
+	info := FileInfo{Size: -1}
+	e, tErr := getEarthdataSession(s)
+
+	if tErr != nil {
+		return info, tErr
+	}
+
+	defer getURLLimiter(s.Url).acquire()()
+
+	request, err := e.newRequest(context.TODO(), http.MethodGet, s.Url)
+
+	if err != nil {
+		return info, taskError{err: err, flag: false}
+	}
+
+	request.Header.Set("Range", "bytes=0-0")
+	response, tErr := e.do(s, time.Minute, request)
+
+	if tErr != nil {
+		return info, tErr
+	}
+
+	response.Body.Close()
+
+	if tErr = earthdataStatus(response); tErr != nil {
+		return info, tErr
+	}
+
+	// e.g., "bytes 0-0/12345"
+	if response.StatusCode == http.StatusPartialContent {
+		contentRange := response.Header.Get("Content-Range")
+
+		if idx := strings.LastIndexByte(contentRange, '/'); idx >= 0 {
+			if size, err := strconv.ParseInt(contentRange[idx+1:], 10, 64); err == nil {
+				info.Size = size
+			}
+		}
+	} else {
+		info.Size = response.ContentLength
+	}
+
+	if t, err := http.ParseTime(response.Header.Get("Last-Modified")); err == nil {
+		info.ModTime = t
+	}
+
+	return info, nil
+}
+
+/***********************************************/
+
+func EarthdataDownload(f *NetworkTask) TaskError {
+	// initialize status of the task
+	var idx int64
+	var flag int
+
+	if f.Continue && f.Writer == nil {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+		info, err := os.Stat(f.Path)
+
+		if err != nil {
+			idx = 0
+		} else {
+			if info.Size() != f.Size { // some error occurs, redownload
+				idx = 0
+				f.Size = 0
+				flag = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+			} else {
+				idx = f.Size
+			}
+		}
+	} else {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		idx = 0
+	}
+
+	e, tErr := getEarthdataSession(&f.Source)
+
+	if tErr != nil {
+		return tErr
+	}
+
+	// make request to download the file
+	limiter := getURLLimiter(f.Source.Url)
+	defer limiter.acquire()()
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	timer := time.AfterFunc(time.Minute, func() { cancel() })
+	defer timer.Stop()
+
+	request, err := e.newRequest(ctx, http.MethodGet, f.Source.Url)
+
+	if err != nil {
+		return taskError{err: err, flag: false}
+	}
+
+	request.Header.Set("Range", fmt.Sprintf("bytes=%d-", idx))
+	response, tErr := e.do(&f.Source, 0, request)
+
+	if tErr != nil {
+		return tErr
+	}
+
+	defer response.Body.Close()
+
+	// the file is already complete if the range from the downloaded size is not satisfiable
+	if response.StatusCode == http.StatusRequestedRangeNotSatisfiable && idx > 0 {
+		return nil
+	} else if tErr = earthdataStatus(response); tErr != nil {
+		return tErr
+	}
+
+	// the whole file is sent if the range is ignored
+	if response.StatusCode == http.StatusOK && idx != 0 {
+		f.Size = 0
+		flag = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+
+	fp, err := f.open(flag)
+
+	if err != nil {
+		return taskError{err: err, flag: false}
+	}
+
+	defer fp.Close()
+
+	var n int64
+	reader := limiter.reader(response.Body)
+
+	for {
+		timer.Reset(30 * time.Second)
+		n, err = io.CopyN(fp, reader, 32*1024)
+		f.Size += n
+
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return taskError{err: err, flag: errors.Is(err, context.Canceled)}
+		}
+	}
+}
+
+/***********************************************/
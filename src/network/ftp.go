@@ -8,17 +8,30 @@ import (
 	"net/textproto"
 	"net/url"
 	"os"
+	"path"
 	"strconv"
 	"strings"
 	"time"
 )
 
+// Common methods of ftpConn and ftpsConn.
+type ftpSession interface {
+	SendCommand(expectCode int, format string, args ...interface{}) (int, string, error)
+	ReadResponse(expectCode int) (int, string, error)
+	DataConn(mode string, ignoreHost bool) (net.Conn, error)
+	HasFeature(name string) bool
+	IsResumable() bool
+	Login(username, password string) error
+	Close() error
+}
+
 type ftpConn struct {
 	conn     net.Conn
 	timeout  time.Duration
 	reader   *textproto.Reader
 	writer   *textproto.Writer
 	features map[string]string
+	noEPSV   bool // whether EPSV is not supported by the server
 }
 
 func NewFTPConn(addr string, timeout time.Duration) (*ftpConn, error) {
@@ -69,6 +82,11 @@ func (c *ftpConn) SendCommand(expectCode int, format string, args ...interface{}
 		return 0, "", err
 	}
 
+	return c.ReadResponse(expectCode)
+}
+
+// read a response from the server, e.g., the reply after a data transfer.
+func (c *ftpConn) ReadResponse(expectCode int) (int, string, error) {
 	c.conn.SetReadDeadline(time.Now().Add(c.timeout))
 	code, msg, err := c.reader.ReadResponse(expectCode)
 
@@ -111,157 +129,313 @@ func (c *ftpConn) IsResumable() bool {
 	return ok && val == "STREAM"
 }
 
-func FTPDownload(f *NetworkTask) TaskError {
-	var offset int64
-	var flag int
-	var err error
+func (c *ftpConn) HasFeature(name string) bool {
+	_, ok := c.features[name]
+	return ok
+}
 
-	if f.Continue {
-		flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
-		info, err := os.Stat(f.Path)
+// log in, and switch to the binary mode.
+func (c *ftpConn) Login(username, password string) error {
+	_, _, err := c.SendCommand(FTPCodeNeedPassword, "USER %s", username)
 
-		if err != nil {
-			offset = 0
-		} else {
-			if info.Size() != f.Size { // some error occurs, redownload
-				offset = 0
-				f.Size = 0
-				flag = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
-			} else {
-				offset = f.Size
-			}
-		}
-	} else {
-		flag = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
-		offset = 0
+	if err != nil {
+		return fmt.Errorf("failed to send USER command, %s", err)
 	}
 
-	username := f.Source.UserName
-	password := f.Source.Password
-
-	rawURL := f.Source.Url
-	pURL, err := url.Parse(rawURL)
+	_, _, err = c.SendCommand(FTPCodeLoggedIn, "PASS %s", password)
 
 	if err != nil {
-		err = fmt.Errorf("falied to parse URL, %s", err)
-		return taskError{err: err, flag: false}
+		return fmt.Errorf("failed to send PASS command, %s", err)
 	}
 
-	addr := pURL.Host
-	path := pURL.Path
+	_, _, err = c.SendCommand(FTPCodeCommandOk, "TYPE I")
 
-	if pURL.Port() == "" {
-		addr += ":21"
+	if err != nil {
+		return fmt.Errorf("failed to send TYPE command, %s", err)
 	}
 
-	conn, err := NewFTPConn(addr, time.Minute)
+	return nil
+}
+
+// open a data connection in the mode of the source.
+func (c *ftpConn) DataConn(mode string, ignoreHost bool) (net.Conn, error) {
+	return openDataConn(c, c.conn, c.timeout, mode, ignoreHost, &c.noEPSV)
+}
+
+// Check whether the reply of the server means that the file is unavailable, e.g., not found.
+func isFileUnavailable(err error) bool {
+	e, ok := err.(*textproto.Error)
+	return ok && e.Code == FTPCodeFileUnavailable
+}
+
+// List the names of files in the directory, via MLSD if supported, otherwise via NLST.
+func listDir(c ftpSession, s *NetworkInfo, dir string) ([]string, error) {
+	dconn, err := c.DataConn(s.FtpMode, s.IgnorePasvHost)
 
 	if err != nil {
-		err = fmt.Errorf("failed to connect to the server, %s", err)
-		return taskError{err: err, flag: true}
+		return nil, err
 	}
 
-	defer conn.Close()
+	defer dconn.Close()
 
-	_, _, err = conn.SendCommand(FTPCodeNeedPassword, "USER %s", username)
+	cmd := "NLST"
 
-	if err != nil {
-		err = fmt.Errorf("failed to send USER command, %s", err)
-		return taskError{err: err, flag: false}
+	if c.HasFeature("MLST") {
+		cmd = "MLSD"
 	}
 
-	_, _, err = conn.SendCommand(FTPCodeLoggedIn, "PASS %s", password)
+	_, _, err = c.SendCommand(FTPCodePositivePreliminary, "%s %s", cmd, dir)
 
 	if err != nil {
-		err = fmt.Errorf("failed to send PASS command, %s", err)
-		return taskError{err: err, flag: false}
+		if isFileUnavailable(err) {
+			return nil, fmt.Errorf("failed to send %s command, %w, %s", cmd, ErrNotFound, err)
+		} else {
+			return nil, fmt.Errorf("failed to send %s command, %s", cmd, err)
+		}
 	}
 
-	_, _, err = conn.SendCommand(FTPCodeCommandOk, "TYPE I")
+	var names []string
+	scanner := bufio.NewScanner(dconn)
+	timer := time.AfterFunc(time.Minute, func() { dconn.Close() })
+	defer timer.Stop()
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if cmd == "MLSD" { // e.g., "type=file;size=1024;modify=20250101000000; name"
+			facts, name, ok := strings.Cut(line, " ")
+
+			if ok && strings.Contains(strings.ToLower(facts), "type=file;") {
+				names = append(names, name)
+			}
+		} else if len(line) != 0 {
+			names = append(names, path.Base(line))
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the list, %s", err)
+	}
+
+	dconn.Close()
+
+	if _, _, err = c.ReadResponse(FTPCodePositive); err != nil {
+		return nil, fmt.Errorf("failed to complete the list, %s", err)
+	}
+
+	return names, nil
+}
+
+func FTPList(s *NetworkInfo) ([]string, TaskError) {
+	return listFiles(s)
+}
+
+func FTPDownload(f *NetworkTask) TaskError {
+	return retrieveFile(f)
+}
+
+func FTPStat(s *NetworkInfo) (FileInfo, TaskError) {
+	return statFile(s)
+}
+
+// Get the size and modification time of the file via SIZE and MDTM with a pooled session,
+// which is used by both FTP and FTPS. Either may be unknown if the command is not supported.
+func statFile(s *NetworkInfo) (FileInfo, TaskError) {
+	info := FileInfo{Size: -1}
+	pURL, err := url.Parse(s.Url)
 
 	if err != nil {
-		err = fmt.Errorf("failed to send TYPE command, %s", err)
-		return taskError{err: err, flag: false}
+		err = fmt.Errorf("falied to parse URL, %s", err)
+		return info, taskError{err: err, flag: false}
 	}
 
-	_, msg, err := conn.SendCommand(FTPCodePassiveMode, "PASV")
+	limiter := getLimiter(pURL.Hostname())
+	defer limiter.acquire()()
+
+	conn, key, tErr := sessions.get(pURL, s)
+
+	if tErr != nil {
+		return info, tErr
+	}
+
+	_, msg, err := conn.SendCommand(FTPCodeFileStatus, "SIZE %s", pURL.Path)
+
+	if err == nil {
+		info.Size, _ = strconv.ParseInt(strings.TrimSpace(msg), 10, 64)
+	} else if isFileUnavailable(err) {
+		sessions.put(key, conn)
+		err = fmt.Errorf("failed to send SIZE command, %w, %s", ErrNotFound, err)
+		return info, taskError{err: err, flag: false}
+	} else if _, ok := err.(*textproto.Error); !ok {
+		sessions.drop(key, conn)
+		err = fmt.Errorf("failed to send SIZE command, %s", err)
+		return info, taskError{err: err, flag: true}
+	}
+
+	// e.g., "20250101120000" or "20250101120000.123"
+	_, msg, err = conn.SendCommand(FTPCodeFileStatus, "MDTM %s", pURL.Path)
+
+	if err == nil {
+		msg = strings.TrimSpace(msg)
+
+		if len(msg) > 14 {
+			msg = msg[:14]
+		}
+
+		if t, err := time.Parse("20060102150405", msg); err == nil {
+			info.ModTime = t
+		}
+	} else if _, ok := err.(*textproto.Error); !ok {
+		sessions.drop(key, conn)
+		err = fmt.Errorf("failed to send MDTM command, %s", err)
+		return info, taskError{err: err, flag: true}
+	}
+
+	sessions.put(key, conn)
+	return info, nil
+}
+
+// List the names of files in the directory with a pooled session, which is used by both FTP and FTPS.
+func listFiles(s *NetworkInfo) ([]string, TaskError) {
+	pURL, err := url.Parse(s.Url)
 
 	if err != nil {
-		err = fmt.Errorf("failed to send PASV command, %s", err)
-		return taskError{err: err, flag: false}
+		err = fmt.Errorf("falied to parse URL, %s", err)
+		return nil, taskError{err: err, flag: false}
 	}
 
-	startIdx := strings.Index(msg, "(")
-	endIdx := strings.LastIndex(msg, ")")
+	limiter := getLimiter(pURL.Hostname())
+	defer limiter.acquire()()
 
-	if startIdx == -1 || endIdx == -1 || startIdx > endIdx {
-		err = fmt.Errorf("failed to get the address of data connection")
-		return taskError{err: err, flag: false}
+	conn, key, tErr := sessions.get(pURL, s)
+
+	if tErr != nil {
+		return nil, tErr
 	}
 
-	addrParts := strings.Split(msg[startIdx+1:endIdx], ",")
+	names, err := listDir(conn, s, pURL.Path)
 
-	if len(addrParts) != 6 {
-		err = fmt.Errorf("failed to get the address of data connection, invalid host")
-		return taskError{err: err, flag: false}
+	if err != nil {
+		if IsNotFound(err) {
+			sessions.put(key, conn)
+		} else {
+			sessions.drop(key, conn)
+		}
+
+		return nil, taskError{err: err, flag: !IsNotFound(err)}
 	}
 
-	host := strings.Join(addrParts[0:4], ".")
+	sessions.put(key, conn)
+	return names, nil
+}
 
-	port := 0
+// Download the file with a pooled session, which is used by both FTP and FTPS. The session is returned into
+// the pool after a complete transfer, or closed if its state is unknown.
+func retrieveFile(f *NetworkTask) TaskError {
+	var offset int64
+	var flag int
+	var err error
 
-	for i, part := range addrParts[4:6] {
-		iport, err := strconv.Atoi(part)
+	if f.Continue && f.Writer == nil {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+		info, err := os.Stat(f.Path)
 
 		if err != nil {
-			err = fmt.Errorf("failed to get the address of data connection, invalid port")
-			return taskError{err: err, flag: false}
+			offset = 0
+		} else {
+			if info.Size() != f.Size { // some error occurs, redownload
+				offset = 0
+				f.Size = 0
+				flag = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+			} else {
+				offset = f.Size
+			}
 		}
+	} else {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		offset = 0
+	}
+
+	pURL, err := url.Parse(f.Source.Url)
 
-		port |= iport << (byte(1-i) * 8)
+	if err != nil {
+		err = fmt.Errorf("falied to parse URL, %s", err)
+		return taskError{err: err, flag: false}
 	}
 
-	if conn.IsResumable() {
-		conn.SendCommand(FTPCodeFileActionPending, "REST %d", offset)
+	limiter := getLimiter(pURL.Hostname())
+	defer limiter.acquire()()
+
+	conn, key, tErr := sessions.get(pURL, &f.Source)
+
+	if tErr != nil {
+		return tErr
 	}
 
-	DataAddr := fmt.Sprintf("[%s]:%d", host, port)
-	dconn, err := net.DialTimeout("tcp", DataAddr, conn.timeout)
+	dconn, err := conn.DataConn(f.Source.FtpMode, f.Source.IgnorePasvHost)
 
 	if err != nil {
-		err = fmt.Errorf("failed to active data connection")
+		sessions.drop(key, conn)
 		return taskError{err: err, flag: false}
 	}
 
 	defer dconn.Close()
 
-	_, _, err = conn.SendCommand(FTPCodeFileStatusOk, "RETR %s", path)
+	if conn.IsResumable() {
+		conn.SendCommand(FTPCodeFileActionPending, "REST %d", offset)
+	}
+
+	_, _, err = conn.SendCommand(FTPCodeFileStatusOk, "RETR %s", pURL.Path)
 
 	if err != nil {
-		err = fmt.Errorf("failed to send RETR command, %s", err)
+		if isFileUnavailable(err) {
+			sessions.put(key, conn)
+			err = fmt.Errorf("failed to send RETR command, %w, %s", ErrNotFound, err)
+		} else {
+			sessions.drop(key, conn)
+			err = fmt.Errorf("failed to send RETR command, %s", err)
+		}
+
 		return taskError{err: err, flag: false}
 	}
 
-	fp, err := os.OpenFile(f.Path, flag, 0664)
+	fp, err := f.open(flag)
 
 	if err != nil {
+		sessions.drop(key, conn)
 		return taskError{err: err, flag: false}
 	}
 
 	defer fp.Close()
 
 	var n int64
+	reader := limiter.reader(dconn)
 	timer := time.AfterFunc(time.Minute, func() { dconn.Close() })
 
 	for {
 		timer.Reset(30 * time.Second)
-		n, err = io.CopyN(fp, dconn, 1024)
+		n, err = io.CopyN(fp, reader, 1024)
 		f.Size += n
 
 		if err == io.EOF {
-			return nil
+			break
 		} else if err != nil {
+			timer.Stop()
+			sessions.drop(key, conn)
 			return taskError{err: err, flag: true}
 		}
 	}
+
+	timer.Stop()
+	dconn.Close()
+
+	// the reply of the completed transfer must be read before the session is reused
+	if _, _, err = conn.ReadResponse(FTPCodePositive); err != nil {
+		sessions.drop(key, conn)
+		err = fmt.Errorf("failed to complete the transfer, %s", err)
+		return taskError{err: err, flag: true}
+	}
+
+	sessions.put(key, conn)
+	return nil
 }
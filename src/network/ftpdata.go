@@ -0,0 +1,346 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/***** CONSTANT ********************************/
+
+// modes of FTP/FTPS data connections
+const (
+	FTPModeAuto    = "auto"    // EPSV, then PASV, then the active mode
+	FTPModePassive = "passive" // EPSV, then PASV
+	FTPModeActive  = "active"  // PORT for IPv4, or EPRT for IPv6
+)
+
+/***** FUNCTION ********************************/
+
+// Open a data connection in the given mode, the passive modes are tried in order, and the active mode is the last resort.
+// If EPSV is not supported, it is marked in noEPSV and skipped for the session later.
+func openDataConn(c ftpSession, ctrl net.Conn, timeout time.Duration, mode string, ignoreHost bool, noEPSV *bool) (net.Conn, error) {
+	var (
+		dconn net.Conn
+		err   error
+		errs  []string
+	)
+
+	if mode != FTPModeActive {
+		if !*noEPSV {
+			if dconn, err = dialEPSV(c, ctrl, timeout); err == nil {
+				return dconn, nil
+			} else if isCommandRejected(err) {
+				*noEPSV = true
+			}
+
+			errs = append(errs, err.Error())
+		}
+
+		if dconn, err = dialPASV(c, ctrl, timeout, ignoreHost); err == nil {
+			return dconn, nil
+		}
+
+		errs = append(errs, err.Error())
+
+		if mode == FTPModePassive {
+			return nil, fmt.Errorf("failed to open data connection, %s", strings.Join(errs, "; "))
+		}
+	}
+
+	if dconn, err = listenActive(c, ctrl, timeout); err == nil {
+		return dconn, nil
+	}
+
+	errs = append(errs, err.Error())
+	return nil, fmt.Errorf("failed to open data connection, %s", strings.Join(errs, "; "))
+}
+
+/***********************************************/
+
+// Check whether the command is rejected by the server, e.g., "500 Unknown command" or "502 Not implemented".
+func isCommandRejected(err error) bool {
+	var e *textproto.Error
+	return errors.As(err, &e) && e.Code >= 500 && e.Code < 510
+}
+
+/***********************************************/
+
+// Open a data connection in the extended passive mode (RFC 2428), which also works for IPv6.
+func dialEPSV(c ftpSession, ctrl net.Conn, timeout time.Duration) (net.Conn, error) {
+	_, msg, err := c.SendCommand(FTPCodeExtendedPassiveMode, "EPSV")
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to send EPSV command, %w", err)
+	}
+
+	port, err := parseEPSV(msg)
+
+	if err != nil {
+		return nil, err
+	}
+
+	host, _, _ := net.SplitHostPort(ctrl.RemoteAddr().String())
+	dconn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), timeout)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to active data connection of EPSV, %s", err)
+	}
+
+	return dconn, nil
+}
+
+/***********************************************/
+
+// Open a data connection in the passive mode. The advertised host is replaced by the host of the control connection
+// if ignoreHost is true, or it is unspecified, or it is private while the server is not, e.g., for servers behind NAT.
+func dialPASV(c ftpSession, ctrl net.Conn, timeout time.Duration, ignoreHost bool) (net.Conn, error) {
+	_, msg, err := c.SendCommand(FTPCodePassiveMode, "PASV")
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to send PASV command, %w", err)
+	}
+
+	host, port, err := parsePASV(msg)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ctrlHost, _, _ := net.SplitHostPort(ctrl.RemoteAddr().String())
+
+	if ip := net.ParseIP(host); ignoreHost || ip == nil || ip.IsUnspecified() ||
+		(ip.IsPrivate() && !net.ParseIP(ctrlHost).IsPrivate()) {
+		host = ctrlHost
+	}
+
+	dconn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), timeout)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to active data connection of PASV, %s", err)
+	}
+
+	return dconn, nil
+}
+
+/***********************************************/
+
+// Get the address of the data connection from the reply of PASV, e.g., "Entering Passive Mode (h1,h2,h3,h4,p1,p2)".
+func parsePASV(msg string) (string, int, error) {
+	startIdx := strings.Index(msg, "(")
+	endIdx := strings.LastIndex(msg, ")")
+
+	if startIdx == -1 || endIdx == -1 || startIdx > endIdx {
+		return "", 0, fmt.Errorf("failed to get the address of data connection")
+	}
+
+	addrParts := strings.Split(msg[startIdx+1:endIdx], ",")
+
+	if len(addrParts) != 6 {
+		return "", 0, fmt.Errorf("failed to get the address of data connection, invalid host")
+	}
+
+	host := strings.Join(addrParts[0:4], ".")
+
+	port := 0
+
+	for i, part := range addrParts[4:6] {
+		iport, err := strconv.Atoi(strings.TrimSpace(part))
+
+		if err != nil || iport < 0 || iport > 255 {
+			return "", 0, fmt.Errorf("failed to get the address of data connection, invalid port")
+		}
+
+		port |= iport << (byte(1-i) * 8)
+	}
+
+	return host, port, nil
+}
+
+/***********************************************/
+
+// Get the port of the data connection from the reply of EPSV, e.g., "Entering Extended Passive Mode (|||6446|)".
+func parseEPSV(msg string) (int, error) {
+	startIdx := strings.Index(msg, "(")
+	endIdx := strings.LastIndex(msg, ")")
+
+	if startIdx == -1 || endIdx == -1 || endIdx-startIdx < 6 {
+		return 0, fmt.Errorf("failed to get the port of data connection")
+	}
+
+	// the delimiter is the first character, and the fields of protocol and address are empty
+	fields := strings.Split(msg[startIdx+2:endIdx], msg[startIdx+1:startIdx+2])
+
+	if len(fields) != 4 {
+		return 0, fmt.Errorf("failed to get the port of data connection, invalid format")
+	}
+
+	port, err := strconv.Atoi(fields[2])
+
+	if err != nil || port <= 0 || port > 65535 {
+		return 0, fmt.Errorf("failed to get the port of data connection, invalid port")
+	}
+
+	return port, nil
+}
+
+/***********************************************/
+
+// Listen on the local address of the control connection, and send PORT (IPv4) or EPRT (IPv6) to the server,
+// which connects to it after the transfer command.
+func listenActive(c ftpSession, ctrl net.Conn, timeout time.Duration) (net.Conn, error) {
+	host, _, _ := net.SplitHostPort(ctrl.LocalAddr().String())
+	ln, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for active data connection, %s", err)
+	}
+
+	addr := ln.Addr().(*net.TCPAddr)
+
+	if ip4 := addr.IP.To4(); ip4 != nil {
+		_, _, err = c.SendCommand(FTPCodeCommandOk, "PORT %d,%d,%d,%d,%d,%d",
+			ip4[0], ip4[1], ip4[2], ip4[3], addr.Port>>8, addr.Port&0xff)
+	} else {
+		_, _, err = c.SendCommand(FTPCodeCommandOk, "EPRT |2|%s|%d|", addr.IP, addr.Port)
+	}
+
+	if err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("failed to send PORT/EPRT command, %s", err)
+	}
+
+	return &activeConn{ln: ln, timeout: timeout}, nil
+}
+
+/***** STRUCT **********************************/
+
+// Data connection in the active mode, which is accepted from the server on the first use.
+type activeConn struct {
+	mutex   sync.Mutex
+	ln      net.Listener
+	conn    net.Conn
+	err     error
+	timeout time.Duration
+}
+
+/***** METHOD **********************************/
+
+// accept the connection from the server, or return the accepted one.
+func (a *activeConn) accept() (net.Conn, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.conn == nil && a.err == nil {
+		if tl, ok := a.ln.(*net.TCPListener); ok {
+			tl.SetDeadline(time.Now().Add(a.timeout))
+		}
+
+		if a.conn, a.err = a.ln.Accept(); a.err != nil {
+			a.err = fmt.Errorf("failed to accept active data connection, %s", a.err)
+		}
+
+		a.ln.Close()
+	}
+
+	return a.conn, a.err
+}
+
+/***********************************************/
+
+func (a *activeConn) Read(b []byte) (int, error) {
+	conn, err := a.accept()
+
+	if err != nil {
+		return 0, err
+	}
+
+	return conn.Read(b)
+}
+
+/***********************************************/
+
+func (a *activeConn) Write(b []byte) (int, error) {
+	conn, err := a.accept()
+
+	if err != nil {
+		return 0, err
+	}
+
+	return conn.Write(b)
+}
+
+/***********************************************/
+
+// close the listener, which also interrupts the waiting for the server, and the accepted connection.
+func (a *activeConn) Close() error {
+	a.ln.Close()
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.conn != nil {
+		return a.conn.Close()
+	}
+
+	return nil
+}
+
+/***********************************************/
+
+func (a *activeConn) LocalAddr() net.Addr {
+	return a.ln.Addr()
+}
+
+/***********************************************/
+
+func (a *activeConn) RemoteAddr() net.Addr {
+	if conn, err := a.accept(); err == nil {
+		return conn.RemoteAddr()
+	}
+
+	return nil
+}
+
+/***********************************************/
+
+func (a *activeConn) SetDeadline(t time.Time) error {
+	conn, err := a.accept()
+
+	if err != nil {
+		return err
+	}
+
+	return conn.SetDeadline(t)
+}
+
+/***********************************************/
+
+func (a *activeConn) SetReadDeadline(t time.Time) error {
+	conn, err := a.accept()
+
+	if err != nil {
+		return err
+	}
+
+	return conn.SetReadDeadline(t)
+}
+
+/***********************************************/
+
+func (a *activeConn) SetWriteDeadline(t time.Time) error {
+	conn, err := a.accept()
+
+	if err != nil {
+		return err
+	}
+
+	return conn.SetWriteDeadline(t)
+}
+
+/***********************************************/
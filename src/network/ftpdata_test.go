@@ -0,0 +1,276 @@
+package network
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+/***** STRUCT **********************************/
+
+// An in-process stand-in of an FTP server, which only serves the data connection commands and RETR.
+type fakeFTPServer struct {
+	ln       net.Listener
+	rejEPSV  bool   // whether EPSV is rejected with "502 Not implemented"
+	pasvHost string // host advertised in the reply of PASV, e.g., "10,1,2,3"
+	payload  string // content sent in the data connection after RETR
+
+	mutex    sync.Mutex
+	commands []string // commands received, without arguments
+}
+
+/***** METHOD **********************************/
+
+func (s *fakeFTPServer) serve(t *testing.T) {
+	conn, err := s.ln.Accept()
+
+	if err != nil {
+		return
+	}
+
+	defer conn.Close()
+
+	var (
+		tc     = textproto.NewConn(conn)
+		dataLn net.Listener // listener of the passive modes
+		dataTo string       // address of the client in the active mode
+	)
+
+	defer func() {
+		if dataLn != nil {
+			dataLn.Close()
+		}
+	}()
+
+	tc.PrintfLine("220 fake FTP server ready")
+
+	for {
+		line, err := tc.ReadLine()
+
+		if err != nil {
+			return
+		}
+
+		cmd, arg, _ := strings.Cut(line, " ")
+
+		s.mutex.Lock()
+		s.commands = append(s.commands, cmd)
+		s.mutex.Unlock()
+
+		switch cmd {
+		case "FEAT":
+			tc.PrintfLine("211-Features:\r\n EPSV\r\n211 End")
+		case "EPSV", "PASV":
+			if cmd == "EPSV" && s.rejEPSV {
+				tc.PrintfLine("502 Not implemented")
+				continue
+			}
+
+			if dataLn != nil {
+				dataLn.Close()
+			}
+
+			if dataLn, err = net.Listen("tcp", "127.0.0.1:0"); err != nil {
+				tc.PrintfLine("425 Can't open data connection")
+				continue
+			}
+
+			port := dataLn.Addr().(*net.TCPAddr).Port
+
+			if cmd == "EPSV" {
+				tc.PrintfLine("229 Entering Extended Passive Mode (|||%d|)", port)
+			} else {
+				tc.PrintfLine("227 Entering Passive Mode (%s,%d,%d)", s.pasvHost, port>>8, port&0xff)
+			}
+		case "PORT":
+			fields := strings.Split(arg, ",")
+
+			if len(fields) != 6 {
+				tc.PrintfLine("501 Syntax error")
+				continue
+			}
+
+			p1, _ := strconv.Atoi(fields[4])
+			p2, _ := strconv.Atoi(fields[5])
+			dataTo = net.JoinHostPort(strings.Join(fields[:4], "."), strconv.Itoa(p1<<8|p2))
+			tc.PrintfLine("200 PORT command successful")
+		case "EPRT":
+			fields := strings.Split(arg, "|")
+
+			if len(fields) != 5 {
+				tc.PrintfLine("501 Syntax error")
+				continue
+			}
+
+			dataTo = net.JoinHostPort(fields[2], fields[3])
+			tc.PrintfLine("200 EPRT command successful")
+		case "RETR":
+			tc.PrintfLine("150 Opening BINARY mode data connection")
+
+			var dconn net.Conn
+
+			if len(dataTo) != 0 {
+				dconn, err = net.DialTimeout("tcp", dataTo, time.Second)
+			} else if dataLn != nil {
+				dconn, err = dataLn.Accept()
+			} else {
+				err = fmt.Errorf("no data connection")
+			}
+
+			if err != nil {
+				t.Errorf("fake server failed to open data connection, %s", err)
+				tc.PrintfLine("425 Can't open data connection")
+				continue
+			}
+
+			io.WriteString(dconn, s.payload)
+			dconn.Close()
+			tc.PrintfLine("226 Transfer complete")
+		default:
+			tc.PrintfLine("502 Not implemented")
+		}
+	}
+}
+
+/***********************************************/
+
+// Get the commands received, e.g., ["FEAT", "EPSV", "PASV", "RETR"].
+func (s *fakeFTPServer) received() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]string(nil), s.commands...)
+}
+
+/***** FUNCTION ********************************/
+
+// Start listening on the address, and the test is skipped if it is not available, e.g., IPv6 is disabled.
+func newFakeFTPServer(t *testing.T, addr string) *fakeFTPServer {
+	ln, err := net.Listen("tcp", addr)
+
+	if err != nil {
+		t.Skipf("failed to listen on %s, %s", addr, err)
+	}
+
+	t.Cleanup(func() { ln.Close() })
+	return &fakeFTPServer{ln: ln, pasvHost: "127,0,0,1", payload: "fake file content"}
+}
+
+/***********************************************/
+
+// Open the data connection in the mode, retrieve a file via it, and check the content.
+func retrieve(t *testing.T, c *ftpConn, mode string, ignoreHost bool, want string) {
+	dconn, err := c.DataConn(mode, ignoreHost)
+
+	if err != nil {
+		t.Fatalf("DataConn(%q, %v): %s", mode, ignoreHost, err)
+	}
+
+	defer dconn.Close()
+
+	if _, _, err = c.SendCommand(FTPCodeFileStatusOk, "RETR /file"); err != nil {
+		t.Fatalf("RETR: %s", err)
+	}
+
+	dconn.SetDeadline(time.Now().Add(5 * time.Second))
+	got, err := io.ReadAll(dconn)
+
+	if err != nil {
+		t.Fatalf("failed to read data connection, %s", err)
+	}
+
+	if string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if _, _, err = c.ReadResponse(FTPCodePositive); err != nil {
+		t.Errorf("failed to complete the transfer, %s", err)
+	}
+}
+
+/***********************************************/
+
+func TestDataConn(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		addr       string // address of the control connection
+		mode       string
+		rejEPSV    bool
+		pasvHost   string
+		ignoreHost bool
+		want       []string // commands received by the server in two transfers
+	}{
+		{"EPSV", "127.0.0.1:0", FTPModeAuto, false, "", false,
+			[]string{"FEAT", "EPSV", "RETR", "EPSV", "RETR"}},
+		{"PASV fallback", "127.0.0.1:0", FTPModePassive, true, "127,0,0,1", false,
+			[]string{"FEAT", "EPSV", "PASV", "RETR", "PASV", "RETR"}},
+		{"PASV private host ignored", "127.0.0.1:0", FTPModeAuto, true, "10,1,2,3", true,
+			[]string{"FEAT", "EPSV", "PASV", "RETR", "PASV", "RETR"}},
+		{"PASV public host ignored", "127.0.0.1:0", FTPModeAuto, true, "192,0,2,1", true,
+			[]string{"FEAT", "EPSV", "PASV", "RETR", "PASV", "RETR"}},
+		{"active PORT", "127.0.0.1:0", FTPModeActive, false, "", false,
+			[]string{"FEAT", "PORT", "RETR", "PORT", "RETR"}},
+		{"active EPRT", "[::1]:0", FTPModeActive, false, "", false,
+			[]string{"FEAT", "EPRT", "RETR", "EPRT", "RETR"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newFakeFTPServer(t, tc.addr)
+			s.rejEPSV = tc.rejEPSV
+
+			if len(tc.pasvHost) != 0 {
+				s.pasvHost = tc.pasvHost
+			}
+
+			done := make(chan struct{})
+
+			go func() {
+				s.serve(t)
+				close(done)
+			}()
+
+			c, err := NewFTPConn(s.ln.Addr().String(), 5*time.Second)
+
+			if err != nil {
+				t.Fatalf("NewFTPConn: %s", err)
+			}
+
+			// EPSV is not sent again after it is rejected
+			retrieve(t, c, tc.mode, tc.ignoreHost, s.payload)
+			retrieve(t, c, tc.mode, tc.ignoreHost, s.payload)
+			c.Close()
+			<-done
+
+			if got := s.received(); strings.Join(got, " ") != strings.Join(tc.want, " ") {
+				t.Errorf("commands received %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+/***********************************************/
+
+func TestParsePASV(t *testing.T) {
+	host, port, err := parsePASV("Entering Passive Mode (10,1,2,3,25,100)")
+
+	if err != nil || host != "10.1.2.3" || port != 25<<8|100 {
+		t.Errorf("parsePASV = %s, %d, %v, want 10.1.2.3, %d", host, port, err, 25<<8|100)
+	}
+}
+
+/***********************************************/
+
+func TestParseEPSV(t *testing.T) {
+	port, err := parseEPSV("Entering Extended Passive Mode (|||6446|)")
+
+	if err != nil || port != 6446 {
+		t.Errorf("parseEPSV = %d, %v, want 6446", port, err)
+	}
+}
+
+/***********************************************/
@@ -3,69 +3,41 @@ package network
 import (
 	"bufio"
 	"crypto/tls"
-	"crypto/x509"
 	"fmt"
-	"io"
 	"net"
 	"net/textproto"
 	"net/url"
-	"os"
-	"strconv"
 	"strings"
-	"sync"
-	"sync/atomic"
 	"time"
 )
 
-var (
-	mutexTLS  sync.Mutex
-	configTLS tls.Config
-	ifInitTLS atomic.Bool
+const (
+	ftpsDefaultPort         = "21"
+	ftpsImplicitDefaultPort = "990"
 )
 
 type ftpsConn struct {
 	rawConn  net.Conn
 	tlsConn  *tls.Conn
+	config   *tls.Config // shared by the data connections to resume the TLS session of the control connection
 	timeout  time.Duration
 	ctrlConn net.Conn
 	reader   *textproto.Reader
 	writer   *textproto.Writer
 	features map[string]string
+	noEPSV   bool // whether EPSV is not supported by the server
 }
 
-func getTLSConfig() (err error) {
-	mutexTLS.Lock()
-	defer mutexTLS.Unlock()
-
-	if ok := ifInitTLS.Load(); ok {
-		return nil
-	}
-
-	configTLS.InsecureSkipVerify = true
-	configTLS.ClientAuth = tls.VerifyClientCertIfGiven
-	configTLS.CipherSuites = []uint16{
-		tls.TLS_RSA_WITH_AES_128_CBC_SHA,
-		tls.TLS_RSA_WITH_AES_256_CBC_SHA,
-		tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
-		tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
-		tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
-		tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
-		tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
-		tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
-	}
-
-	configTLS.RootCAs, err = x509.SystemCertPool()
-
-	if err != nil {
-		return fmt.Errorf("failed to get root CAs, %s", err.Error())
-	}
-
-	configTLS.ClientCAs = configTLS.RootCAs
-
-	return nil
+// Check whether the FTPS of the source is implicit, i.e., TLS from the beginning instead of "AUTH TLS", which is on port 990 by default.
+func isImplicitFtps(s *NetworkInfo, pURL *url.URL) bool {
+	return s.ImplicitFtps || pURL.Port() == ftpsImplicitDefaultPort
 }
 
-func NewFTPSConn(addr string, timeout time.Duration) (*ftpsConn, error) {
+// Connect to the FTPS server, the config must have its own session cache, which is used by the data connections.
+// The TLS sessions are resumed only by session tickets, since the ones of TLS 1.2 are not resumed by session IDs in Golang,
+// so the servers requiring the reuse but issuing no tickets, e.g., vsftpd with "require_ssl_reuse" and tickets disabled,
+// reject the data connections.
+func NewFTPSConn(addr string, config *tls.Config, implicit bool, timeout time.Duration) (*ftpsConn, error) {
 	c := new(ftpsConn)
 	var err error
 
@@ -75,38 +47,47 @@ func NewFTPSConn(addr string, timeout time.Duration) (*ftpsConn, error) {
 		return nil, err
 	}
 
+	c.config = config
 	c.timeout = timeout
-	c.setCtrlConn(c.rawConn)
 	c.features = make(map[string]string)
-	c.ctrlConn.SetReadDeadline(time.Now().Add(c.timeout))
-	_, _, err = c.reader.ReadResponse(FTPCodeServiceReady)
 
-	if err != nil {
-		c.Close()
-		err = fmt.Errorf("failed to get welcome message, %s", err)
-		return nil, err
+	if implicit {
+		c.tlsConn = tls.Client(c.rawConn, c.config)
+		c.setCtrlConn(c.tlsConn)
+	} else {
+		c.setCtrlConn(c.rawConn)
 	}
 
-	_, _, err = c.SendCommand(FTPCodeAuthOk, "AUTH TLS")
+	c.ctrlConn.SetReadDeadline(time.Now().Add(c.timeout))
+	_, _, err = c.reader.ReadResponse(FTPCodeServiceReady)
 
 	if err != nil {
 		c.Close()
-		err = fmt.Errorf("failed to send AUTH command, %s", err)
+		err = fmt.Errorf("failed to get welcome message, %s", err)
 		return nil, err
 	}
 
-	if ok := ifInitTLS.Load(); !ok {
-		err = getTLSConfig()
+	if !implicit {
+		_, _, err = c.SendCommand(FTPCodeAuthOk, "AUTH TLS")
 
 		if err != nil {
 			c.Close()
-			err = fmt.Errorf("failed to get TLS config, %s", err)
+			err = fmt.Errorf("failed to send AUTH command, %s", err)
 			return nil, err
 		}
+
+		c.tlsConn = tls.Client(c.rawConn, c.config)
+		c.setCtrlConn(c.tlsConn)
 	}
 
-	c.tlsConn = tls.Client(c.rawConn, &configTLS)
-	c.setCtrlConn(c.tlsConn)
+	// the handshake is done before the commands, so that the failure of verification is reported as it is
+	c.tlsConn.SetDeadline(time.Now().Add(c.timeout))
+
+	if err = c.tlsConn.Handshake(); err != nil {
+		c.Close()
+		err = fmt.Errorf("failed to handshake, %w", err)
+		return nil, err
+	}
 
 	err = c.fetchFeatures()
 
@@ -165,6 +146,10 @@ func (c *ftpsConn) SendCommand(expectCode int, format string, args ...interface{
 		return 0, "", err
 	}
 
+	return c.ReadResponse(expectCode)
+}
+
+func (c *ftpsConn) ReadResponse(expectCode int) (int, string, error) {
 	c.ctrlConn.SetReadDeadline(time.Now().Add(c.timeout))
 	code, msg, err := c.reader.ReadResponse(expectCode)
 
@@ -179,172 +164,65 @@ func (c *ftpsConn) Close() error {
 	return c.ctrlConn.Close()
 }
 
-func FTPSDownload(f *NetworkTask) TaskError {
-	var offset int64
-	var flag int
-	var err error
-
-	if f.Continue {
-		flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
-		info, err := os.Stat(f.Path)
-
-		if err != nil {
-			offset = 0
-		} else {
-			if info.Size() != f.Size { // some error occurs, redownload
-				offset = 0
-				f.Size = 0
-				flag = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
-			} else {
-				offset = f.Size
-			}
-		}
-	} else {
-		flag = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
-		offset = 0
-	}
-
-	username := f.Source.UserName
-	password := f.Source.Password
-
-	rawURL := f.Source.Url
-	pURL, err := url.Parse(rawURL)
-
-	if err != nil {
-		err = fmt.Errorf("falied to parse URL, %s", err)
-		return taskError{err: err, flag: false}
-	}
-
-	addr := pURL.Host
-	path := pURL.Path
-
-	if pURL.Port() == "" {
-		addr += ":21"
-	}
-
-	conn, err := NewFTPSConn(addr, time.Minute)
-
-	if err != nil {
-		err = fmt.Errorf("failed to connect to the server, %s", err)
-		return taskError{err: err, flag: true}
-	}
-
-	defer conn.Close()
-
-	_, _, err = conn.SendCommand(FTPCodeNeedPassword, "USER %s", username)
-
-	if err != nil {
-		err = fmt.Errorf("failed to send USER command, %s", err)
-		return taskError{err: err, flag: false}
-	}
-
-	_, _, err = conn.SendCommand(FTPCodeLoggedIn, "PASS %s", password)
-
-	if err != nil {
-		err = fmt.Errorf("failed to send PASS command, %s", err)
-		return taskError{err: err, flag: false}
-	}
+func (c *ftpsConn) HasFeature(name string) bool {
+	_, ok := c.features[name]
+	return ok
+}
 
-	_, _, err = conn.SendCommand(FTPCodePositive, "PBSZ 0")
+// log in, protect the data channel, and switch to the binary mode.
+func (c *ftpsConn) Login(username, password string) error {
+	_, _, err := c.SendCommand(FTPCodeNeedPassword, "USER %s", username)
 
 	if err != nil {
-		err = fmt.Errorf("failed to send PBSZ command, %s", err)
-		return taskError{err: err, flag: false}
+		return fmt.Errorf("failed to send USER command, %s", err)
 	}
 
-	_, _, err = conn.SendCommand(FTPCodePositive, "PROT P")
+	_, _, err = c.SendCommand(FTPCodeLoggedIn, "PASS %s", password)
 
 	if err != nil {
-		err = fmt.Errorf("failed to send PORT command, %s", err)
-		return taskError{err: err, flag: false}
+		return fmt.Errorf("failed to send PASS command, %s", err)
 	}
 
-	_, _, err = conn.SendCommand(FTPCodeCommandOk, "TYPE I")
+	_, _, err = c.SendCommand(FTPCodePositive, "PBSZ 0")
 
 	if err != nil {
-		err = fmt.Errorf("failed to send TYPE command, %s", err)
-		return taskError{err: err, flag: false}
+		return fmt.Errorf("failed to send PBSZ command, %s", err)
 	}
 
-	_, msg, err := conn.SendCommand(FTPCodePassiveMode, "PASV")
+	_, _, err = c.SendCommand(FTPCodePositive, "PROT P")
 
 	if err != nil {
-		err = fmt.Errorf("failed to send PASV command, %s", err)
-		return taskError{err: err, flag: false}
-	}
-
-	startIdx := strings.Index(msg, "(")
-	endIdx := strings.LastIndex(msg, ")")
-
-	if startIdx == -1 || endIdx == -1 || startIdx > endIdx {
-		err = fmt.Errorf("failed to get the address of data connection")
-		return taskError{err: err, flag: false}
-	}
-
-	addrParts := strings.Split(msg[startIdx+1:endIdx], ",")
-
-	if len(addrParts) != 6 {
-		err = fmt.Errorf("failed to get the address of data connection, invalid host")
-		return taskError{err: err, flag: false}
-	}
-
-	host := strings.Join(addrParts[0:4], ".")
-
-	port := 0
-
-	for i, part := range addrParts[4:6] {
-		iport, err := strconv.Atoi(part)
-
-		if err != nil {
-			err = fmt.Errorf("failed to get the address of data connection, invalid port")
-			return taskError{err: err, flag: false}
-		}
-
-		port |= iport << (byte(1-i) * 8)
-	}
-
-	if conn.IsResumable() {
-		conn.SendCommand(FTPCodeFileActionPending, "REST %d", offset)
+		return fmt.Errorf("failed to send PORT command, %s", err)
 	}
 
-	DataAddr := fmt.Sprintf("[%s]:%d", host, port)
-	dconn, err := net.DialTimeout("tcp", DataAddr, conn.timeout)
+	_, _, err = c.SendCommand(FTPCodeCommandOk, "TYPE I")
 
 	if err != nil {
-		err = fmt.Errorf("failed to active data connection")
-		return taskError{err: err, flag: false}
+		return fmt.Errorf("failed to send TYPE command, %s", err)
 	}
 
-	dconn = tls.Client(dconn, &configTLS)
-	defer dconn.Close()
-
-	_, _, err = conn.SendCommand(FTPCodeFileStatusOk, "RETR %s", path)
-
-	if err != nil {
-		err = fmt.Errorf("failed to send RETR command, %s", err)
-		return taskError{err: err, flag: false}
-	}
+	return nil
+}
 
-	fp, err := os.OpenFile(f.Path, flag, 0664)
+// open a data connection in the mode of the source, which is protected by TLS.
+func (c *ftpsConn) DataConn(mode string, ignoreHost bool) (net.Conn, error) {
+	dconn, err := openDataConn(c, c.ctrlConn, c.timeout, mode, ignoreHost, &c.noEPSV)
 
 	if err != nil {
-		return taskError{err: err, flag: false}
+		return nil, err
 	}
 
-	defer fp.Close()
+	return tls.Client(dconn, c.config), nil
+}
 
-	var n int64
-	timer := time.AfterFunc(time.Minute, func() { dconn.Close() })
+func FTPSList(s *NetworkInfo) ([]string, TaskError) {
+	return listFiles(s)
+}
 
-	for {
-		timer.Reset(30 * time.Second)
-		n, err = io.CopyN(fp, dconn, 1024)
-		f.Size += n
+func FTPSDownload(f *NetworkTask) TaskError {
+	return retrieveFile(f)
+}
 
-		if err == io.EOF {
-			return nil
-		} else if err != nil {
-			return taskError{err: err, flag: true}
-		}
-	}
+func FTPSStat(s *NetworkInfo) (FileInfo, TaskError) {
+	return statFile(s)
 }
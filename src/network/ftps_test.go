@@ -0,0 +1,214 @@
+package network
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+/***** FUNCTION ********************************/
+
+// Create a self-signed certificate of 127.0.0.1, and save it into a PEM file as the CA of the client.
+func newTestCert(t *testing.T) (tls.Certificate, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fake FTPS server"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+
+	if err = os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, caFile
+}
+
+/***********************************************/
+
+// Serve one control connection of explicit FTPS, and the data connections not resuming the TLS session of it are rejected,
+// as vsftpd does with "require_ssl_reuse".
+func serveFTPS(ln net.Listener, config *tls.Config, payload string) {
+	conn, err := ln.Accept()
+
+	if err != nil {
+		return
+	}
+
+	defer conn.Close()
+
+	var (
+		tc     = textproto.NewConn(conn)
+		dataLn net.Listener
+	)
+
+	defer func() {
+		if dataLn != nil {
+			dataLn.Close()
+		}
+	}()
+
+	tc.PrintfLine("220 fake FTPS server ready")
+
+	for {
+		line, err := tc.ReadLine()
+
+		if err != nil {
+			return
+		}
+
+		switch cmd, _, _ := strings.Cut(line, " "); cmd {
+		case "AUTH":
+			tc.PrintfLine("234 Proceed with negotiation")
+			conn = tls.Server(conn, config)
+			defer conn.Close()
+			tc = textproto.NewConn(conn)
+		case "FEAT":
+			tc.PrintfLine("211-Features:\r\n EPSV\r\n211 End")
+		case "USER":
+			tc.PrintfLine("331 Please specify the password")
+		case "PASS":
+			tc.PrintfLine("230 Login successful")
+		case "PBSZ", "PROT", "TYPE":
+			tc.PrintfLine("200 OK")
+		case "EPSV":
+			if dataLn, err = net.Listen("tcp", "127.0.0.1:0"); err != nil {
+				tc.PrintfLine("425 Can't open data connection")
+				continue
+			}
+
+			tc.PrintfLine("229 Entering Extended Passive Mode (|||%d|)", dataLn.Addr().(*net.TCPAddr).Port)
+		case "RETR":
+			tc.PrintfLine("150 Opening BINARY mode data connection")
+			dconn, err := dataLn.Accept()
+
+			if err != nil {
+				tc.PrintfLine("425 Can't open data connection")
+				continue
+			}
+
+			tlsConn := tls.Server(dconn, config)
+			tlsConn.SetDeadline(time.Now().Add(5 * time.Second))
+
+			if err = tlsConn.Handshake(); err != nil || !tlsConn.ConnectionState().DidResume {
+				dconn.Close()
+				tc.PrintfLine("522 SSL connection failed: session reuse required")
+				continue
+			}
+
+			io.WriteString(tlsConn, payload)
+			tlsConn.Close()
+			tc.PrintfLine("226 Transfer complete")
+		default:
+			tc.PrintfLine("502 Not implemented")
+		}
+	}
+}
+
+/***********************************************/
+
+// Session reuse on the data channel works with the servers issuing session tickets, but not with the ones only reusing session IDs.
+func TestFTPSSessionReuse(t *testing.T) {
+	cert, caFile := newTestCert(t)
+	settings := &TLSConfig{CAFile: caFile}
+
+	for _, tc := range []struct {
+		name       string
+		maxVersion uint16
+		noTickets  bool
+		wantErr    bool
+	}{
+		{"TLS 1.2 with tickets", tls.VersionTLS12, false, false},
+		{"TLS 1.3", tls.VersionTLS13, false, false},
+		{"TLS 1.2 with session IDs only", tls.VersionTLS12, true, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+
+			if err != nil {
+				t.Skipf("failed to listen, %s", err)
+			}
+
+			defer ln.Close()
+
+			config := &tls.Config{Certificates: []tls.Certificate{cert}, MaxVersion: tc.maxVersion, SessionTicketsDisabled: tc.noTickets}
+			payload := "fake file content"
+			go serveFTPS(ln, config, payload)
+
+			s := NetworkInfo{Url: "ftps://" + ln.Addr().String() + "/file", UserName: "anonymous", Password: "anonymous", TLS: settings}
+			pURL, _ := url.Parse(s.Url)
+			c, tErr := dialSession(pURL, &s)
+
+			if tErr != nil {
+				t.Fatalf("dialSession: %s", tErr)
+			}
+
+			defer c.Close()
+
+			// the second data connection resumes the session as well
+			for i := 0; i < 2; i++ {
+				dconn, err := c.DataConn(FTPModeAuto, false)
+
+				if err != nil {
+					t.Fatalf("DataConn: %s", err)
+				}
+
+				if _, _, err = c.SendCommand(FTPCodeFileStatusOk, "RETR /file"); err != nil {
+					t.Fatalf("RETR: %s", err)
+				}
+
+				dconn.SetDeadline(time.Now().Add(5 * time.Second))
+				got, _ := io.ReadAll(dconn)
+				dconn.Close()
+				_, _, err = c.ReadResponse(FTPCodePositive)
+
+				if tc.wantErr {
+					if err == nil || !strings.Contains(err.Error(), "522") {
+						t.Errorf("transfer %d: %v, want the rejection of session reuse", i, err)
+					}
+
+					return
+				}
+
+				if err != nil || string(got) != payload {
+					t.Fatalf("transfer %d: got %q, %v, want %q", i, got, err, payload)
+				}
+			}
+		})
+	}
+}
+
+/***********************************************/
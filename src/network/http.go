@@ -5,16 +5,130 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"path"
+	"regexp"
+	"strings"
 	"time"
 )
 
+// List the names of files linked in the index page of the directory.
+func HTTPList(s *NetworkInfo) ([]string, TaskError) {
+	defer getURLLimiter(s.Url).acquire()()
+
+	client, tErr := httpClient(s, time.Minute)
+
+	if tErr != nil {
+		return nil, tErr
+	}
+
+	request, err := http.NewRequest(http.MethodGet, s.Url, nil)
+
+	if err != nil {
+		return nil, taskError{err: err, flag: false}
+	}
+
+	request.Header.Add("User-Agent", HTTPUserAgent)
+	response, err := client.Do(request)
+
+	if err != nil {
+		return nil, taskError{err: err, flag: !isCertError(err)}
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound || response.StatusCode == http.StatusGone {
+		err = fmt.Errorf("%w, response status code %d", ErrNotFound, response.StatusCode)
+		return nil, taskError{err: err, flag: false}
+	} else if response.StatusCode != http.StatusOK {
+		err = fmt.Errorf("invalid response status code %d", response.StatusCode)
+		return nil, taskError{err: err, flag: true}
+	}
+
+	body, err := io.ReadAll(response.Body)
+
+	if err != nil {
+		return nil, taskError{err: err, flag: true}
+	}
+
+	return parseIndexLinks(body), nil
+}
+
+// Get the names of files linked in the index page.
+func parseIndexLinks(body []byte) []string {
+	var names []string
+	hrefExp := regexp.MustCompile(`(?i)href\s*=\s*["']([^"']+)["']`)
+
+	for _, matched := range hrefExp.FindAllStringSubmatch(string(body), -1) {
+		href := matched[1]
+
+		// skip sub-directories, sorting links and anchors
+		if strings.HasSuffix(href, "/") || strings.HasPrefix(href, "?") || strings.HasPrefix(href, "#") {
+			continue
+		}
+
+		if idx := strings.IndexAny(href, "?#"); idx >= 0 {
+			href = href[:idx]
+		}
+
+		if name, err := url.PathUnescape(path.Base(href)); err == nil {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// Get the size and modification time of the file from "Content-Length" and "Last-Modified" in the response of HEAD.
+func HTTPStat(s *NetworkInfo) (FileInfo, TaskError) {
+	info := FileInfo{Size: -1}
+	defer getURLLimiter(s.Url).acquire()()
+
+	client, tErr := httpClient(s, time.Minute)
+
+	if tErr != nil {
+		return info, tErr
+	}
+
+	request, err := http.NewRequest(http.MethodHead, s.Url, nil)
+
+	if err != nil {
+		return info, taskError{err: err, flag: false}
+	}
+
+	request.Header.Add("User-Agent", HTTPUserAgent)
+	response, err := client.Do(request)
+
+	if err != nil {
+		return info, taskError{err: err, flag: !isCertError(err)}
+	}
+
+	response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound || response.StatusCode == http.StatusGone {
+		err = fmt.Errorf("%w, response status code %d", ErrNotFound, response.StatusCode)
+		return info, taskError{err: err, flag: false}
+	} else if response.StatusCode != http.StatusOK {
+		err = fmt.Errorf("invalid response status code %d", response.StatusCode)
+		return info, taskError{err: err, flag: true}
+	}
+
+	info.Size = response.ContentLength
+
+	if t, err := http.ParseTime(response.Header.Get("Last-Modified")); err == nil {
+		info.ModTime = t
+	}
+
+	return info, nil
+}
+
 func HTTPDownload(f *NetworkTask) TaskError {
 	var idx int64
 	var flag int
 	var err error
 
-	if f.Continue {
+	if f.Continue && f.Writer == nil {
 		flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
 		info, err := os.Stat(f.Path)
 
@@ -34,7 +148,15 @@ func HTTPDownload(f *NetworkTask) TaskError {
 		idx = 0
 	}
 
-	client := http.Client{}
+	limiter := getURLLimiter(f.Source.Url)
+	defer limiter.acquire()()
+
+	client, tErr := httpClient(&f.Source, 0)
+
+	if tErr != nil {
+		return tErr
+	}
+
 	ctx, cancel := context.WithCancel(context.TODO())
 	timer := time.AfterFunc(time.Minute, func() { cancel() })
 
@@ -46,10 +168,15 @@ func HTTPDownload(f *NetworkTask) TaskError {
 	response, err := client.Do(request)
 
 	if err != nil {
-		return taskError{err: err, flag: true}
-	} else if response.StatusCode == http.StatusRequestedRangeNotSatisfiable {
-		request.Body.Close()
+		return taskError{err: err, flag: !isCertError(err)}
+	} else if response.StatusCode == http.StatusRequestedRangeNotSatisfiable && idx > 0 {
+		// the file is already complete if the range from the downloaded size is not satisfiable
+		response.Body.Close()
 		return nil
+	} else if response.StatusCode == http.StatusNotFound || response.StatusCode == http.StatusGone {
+		response.Body.Close()
+		err = fmt.Errorf("%w, response status code %d", ErrNotFound, response.StatusCode)
+		return taskError{err: err, flag: false}
 	} else if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusPartialContent {
 		response.Body.Close()
 		err = fmt.Errorf("invalid response status code %d", response.StatusCode)
@@ -58,7 +185,7 @@ func HTTPDownload(f *NetworkTask) TaskError {
 
 	defer response.Body.Close()
 
-	fp, err := os.OpenFile(f.Path, flag, 0664)
+	fp, err := f.open(flag)
 
 	if err != nil {
 		return taskError{err: err, flag: false}
@@ -67,10 +194,11 @@ func HTTPDownload(f *NetworkTask) TaskError {
 	defer fp.Close()
 
 	var n int64
+	reader := limiter.reader(response.Body)
 
 	for {
 		timer.Reset(30 * time.Second)
-		n, err = io.CopyN(fp, response.Body, 1024)
+		n, err = io.CopyN(fp, reader, 1024)
 		f.Size += n
 
 		if err == io.EOF {
@@ -0,0 +1,60 @@
+package network
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// A server replying "416 Range Not Satisfiable" to every request.
+func newRangeNotSatisfiableServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+	}))
+
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+/***********************************************/
+
+func TestDownloadRangeNotSatisfiable(t *testing.T) {
+	srv := newRangeNotSatisfiableServer(t)
+
+	for _, tc := range []struct {
+		name     string
+		download func(f *NetworkTask) TaskError
+	}{
+		{"HTTP", HTTPDownload},
+		{"Earthdata", EarthdataDownload},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			source := NetworkInfo{Url: srv.URL + "/file.gz", Token: "test-token"}
+
+			// a fresh download is not complete
+			var buf bytes.Buffer
+
+			if tErr := tc.download(&NetworkTask{Source: source, Writer: &buf}); tErr == nil {
+				t.Errorf("fresh download with 416 succeeded, want an error")
+			}
+
+			// the resumed download is complete
+			path := filepath.Join(t.TempDir(), "file.gz")
+
+			if err := os.WriteFile(path, []byte("downloaded"), 0664); err != nil {
+				t.Fatal(err)
+			}
+
+			f := &NetworkTask{Source: source, Path: path, Size: 10, Continue: true}
+
+			if tErr := tc.download(f); tErr != nil {
+				t.Errorf("resumed download with 416 failed, %s", tErr)
+			}
+		})
+	}
+}
+
+/***********************************************/
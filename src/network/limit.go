@@ -0,0 +1,194 @@
+package network
+
+import (
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/***** STRUCT **********************************/
+
+// Limits of requests to one host, zero means unlimited.
+type HostLimit struct {
+	MaxConn   int     // maximum number of concurrent connections
+	Rate      float64 // maximum number of requests per second
+	Bandwidth int64   // maximum number of bytes per second, shared by all connections
+}
+
+/***********************************************/
+
+type hostLimiter struct {
+	limit    HostLimit
+	slots    chan struct{}
+	busy     atomic.Int32
+	mutex    sync.Mutex
+	nextReq  time.Time // earliest time of the next request
+	nextRead time.Time // time when the bytes read so far are allowed
+}
+
+/***** VARIABLE ********************************/
+
+var (
+	mutexLimit sync.Mutex
+	limiters   = make(map[string]*hostLimiter) // key: host name, "*" for all other hosts
+)
+
+/***** FUNCTION ********************************/
+
+// Set the limits of the host, the host "*" gives the limits of each host not set explicitly.
+func SetHostLimit(host string, limit HostLimit) {
+	mutexLimit.Lock()
+	defer mutexLimit.Unlock()
+
+	limiters[strings.ToLower(host)] = newHostLimiter(limit)
+}
+
+/***********************************************/
+
+func newHostLimiter(limit HostLimit) *hostLimiter {
+	l := &hostLimiter{limit: limit}
+
+	if limit.MaxConn > 0 {
+		l.slots = make(chan struct{}, limit.MaxConn)
+	}
+
+	return l
+}
+
+/***********************************************/
+
+// get the limiter of the host, nil if the host is not limited.
+func getLimiter(host string) *hostLimiter {
+	host = strings.ToLower(host)
+
+	mutexLimit.Lock()
+	defer mutexLimit.Unlock()
+
+	if l, ok := limiters[host]; ok {
+		return l
+	}
+
+	// each host has its own limiter with the default limits
+	if l, ok := limiters["*"]; ok {
+		limiters[host] = newHostLimiter(l.limit)
+		return limiters[host]
+	}
+
+	return nil
+}
+
+/***********************************************/
+
+// get the limiter of the host in the url.
+func getURLLimiter(rawURL string) *hostLimiter {
+	pURL, err := url.Parse(rawURL)
+
+	if err != nil {
+		return nil
+	}
+
+	return getLimiter(pURL.Hostname())
+}
+
+/***********************************************/
+
+// Check whether all connections to the host are in use, then a new request has to wait.
+func HostBusy(host string) bool {
+	l := getLimiter(host)
+	return l != nil && l.slots != nil && int(l.busy.Load()) >= l.limit.MaxConn
+}
+
+/***** METHOD **********************************/
+
+// wait for a free connection and the next request allowed, and the returned function must be called when done.
+func (l *hostLimiter) acquire() (release func()) {
+	if l == nil {
+		return func() {}
+	}
+
+	if l.slots != nil {
+		l.slots <- struct{}{}
+	}
+
+	l.busy.Add(1)
+
+	if l.limit.Rate > 0 {
+		l.mutex.Lock()
+		now := time.Now()
+
+		if l.nextReq.Before(now) {
+			l.nextReq = now
+		}
+
+		wait := l.nextReq.Sub(now)
+		l.nextReq = l.nextReq.Add(time.Duration(float64(time.Second) / l.limit.Rate))
+		l.mutex.Unlock()
+
+		time.Sleep(wait)
+	}
+
+	return func() {
+		l.busy.Add(-1)
+
+		if l.slots != nil {
+			<-l.slots
+		}
+	}
+}
+
+/***********************************************/
+
+// maximum number of connections to the host, or the given number if it is smaller.
+func (l *hostLimiter) maxConn(num int) int {
+	if l != nil && l.limit.MaxConn > 0 && l.limit.MaxConn < num {
+		return l.limit.MaxConn
+	}
+
+	return num
+}
+
+/***********************************************/
+
+// wrap the reader, so that the reading from the host does not exceed the bandwidth.
+func (l *hostLimiter) reader(r io.Reader) io.Reader {
+	if l == nil || l.limit.Bandwidth <= 0 {
+		return r
+	}
+
+	return limitedReader{r: r, l: l}
+}
+
+/***** STRUCT **********************************/
+
+type limitedReader struct {
+	r io.Reader
+	l *hostLimiter
+}
+
+/***** METHOD **********************************/
+
+func (r limitedReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+
+	if n > 0 {
+		r.l.mutex.Lock()
+		now := time.Now()
+
+		if r.l.nextRead.Before(now) {
+			r.l.nextRead = now
+		}
+
+		r.l.nextRead = r.l.nextRead.Add(time.Duration(float64(n) / float64(r.l.limit.Bandwidth) * float64(time.Second)))
+		wait := r.l.nextRead.Sub(now)
+		r.l.mutex.Unlock()
+
+		time.Sleep(wait)
+	}
+
+	return n, err
+}
+
+/***********************************************/
@@ -0,0 +1,150 @@
+package network
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+/***** STRUCT **********************************/
+
+// Entry of a machine in the .netrc file.
+type NetrcEntry struct {
+	Machine  string // empty for the default entry
+	Login    string
+	Password string
+}
+
+/***** FUNCTION ********************************/
+
+// Get the path of the .netrc file, which is given by $NETRC, or "~/.netrc" ("~/_netrc" on Windows) by default.
+func netrcPath() string {
+	if p := os.Getenv("NETRC"); len(p) != 0 {
+		return p
+	}
+
+	home, err := os.UserHomeDir()
+
+	if err != nil {
+		return ""
+	}
+
+	if runtime.GOOS == "windows" {
+		if p := filepath.Join(home, "_netrc"); fileExists(p) {
+			return p
+		}
+	}
+
+	return filepath.Join(home, ".netrc")
+}
+
+/***********************************************/
+
+func fileExists(p string) bool {
+	_, err := os.Stat(p)
+	return err == nil
+}
+
+/***********************************************/
+
+// Parse the .netrc file, where the tokens are separated by white spaces, and the macros are skipped.
+func ParseNetrc(p string) ([]NetrcEntry, error) {
+	data, err := os.ReadFile(p)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []NetrcEntry
+	var entry *NetrcEntry
+
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+
+	for i := 0; i < len(lines); i++ {
+		fields := strings.Fields(lines[i])
+
+		for j := 0; j < len(fields); j++ {
+			if strings.HasPrefix(fields[j], "#") {
+				break
+			}
+
+			// the value of the token, empty if missing
+			next := func() string {
+				if j++; j < len(fields) {
+					return fields[j]
+				}
+
+				return ""
+			}
+
+			switch fields[j] {
+			case "machine":
+				entries = append(entries, NetrcEntry{Machine: strings.ToLower(next())})
+				entry = &entries[len(entries)-1]
+			case "default":
+				entries = append(entries, NetrcEntry{})
+				entry = &entries[len(entries)-1]
+			case "login":
+				if val := next(); entry != nil {
+					entry.Login = val
+				}
+			case "password":
+				if val := next(); entry != nil {
+					entry.Password = val
+				}
+			case "account":
+				next()
+			case "macdef":
+				// the macro ends with an empty line
+				for i++; i < len(lines) && len(strings.TrimSpace(lines[i])) != 0; i++ {
+				}
+
+				j = len(fields)
+			}
+		}
+	}
+
+	return entries, nil
+}
+
+/***********************************************/
+
+// Look up the login and password of the host in the .netrc file. If login is not empty, only the entry of the login is matched.
+// The default entry is used if no machine matches.
+func LookupNetrc(host, login string) (NetrcEntry, bool) {
+	p := netrcPath()
+
+	if len(p) == 0 {
+		return NetrcEntry{}, false
+	}
+
+	entries, err := ParseNetrc(p)
+
+	if err != nil {
+		return NetrcEntry{}, false
+	}
+
+	var def *NetrcEntry
+	host = strings.ToLower(host)
+
+	for i, entry := range entries {
+		if len(login) != 0 && entry.Login != login {
+			continue
+		}
+
+		if entry.Machine == host {
+			return entry, true
+		} else if len(entry.Machine) == 0 && def == nil {
+			def = &entries[i]
+		}
+	}
+
+	if def != nil {
+		return *def, true
+	}
+
+	return NetrcEntry{}, false
+}
+
+/***********************************************/
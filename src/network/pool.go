@@ -0,0 +1,286 @@
+package network
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+/***** CONSTANT ********************************/
+
+const (
+	DefaultSessionNum    = 4                // default maximum number of FTP/FTPS sessions per host
+	sessionKeepAlive     = 30 * time.Second // interval of sending NOOP on idle sessions
+	sessionMaxIdleTime   = 5 * time.Minute  // idle sessions are closed after this time
+	sessionCheckInterval = 5 * time.Second
+	sessionDialTimeout   = time.Minute
+)
+
+/***** STRUCT **********************************/
+
+// Authenticated FTP/FTPS sessions of one server, which are shared by goroutines and reused across files.
+type hostSessions struct {
+	num  int // number of sessions, both idle and in use
+	idle []idleSession
+	cond *sync.Cond
+}
+
+/***********************************************/
+
+type idleSession struct {
+	session ftpSession
+	since   time.Time // time when the session became idle
+	ping    time.Time // time of the last command
+}
+
+/***********************************************/
+
+type sessionPool struct {
+	mutex  sync.Mutex
+	once   sync.Once
+	maxNum int
+	hosts  map[string]*hostSessions // key: scheme, username, address of the server and TLS settings
+}
+
+/***** VARIABLE ********************************/
+
+var sessions = sessionPool{maxNum: DefaultSessionNum, hosts: make(map[string]*hostSessions)}
+
+/***** FUNCTION ********************************/
+
+// Set the maximum number of FTP/FTPS sessions per host.
+func SetMaxSessionNum(num int) {
+	sessions.mutex.Lock()
+	defer sessions.mutex.Unlock()
+
+	sessions.maxNum = num
+}
+
+/***********************************************/
+
+// Close all idle FTP/FTPS sessions and SFTP clients, e.g., before exiting.
+func CloseSessions() {
+	closeSFTPClients()
+
+	sessions.mutex.Lock()
+	defer sessions.mutex.Unlock()
+
+	for _, h := range sessions.hosts {
+		for _, s := range h.idle {
+			s.session.SendCommand(FTPCodePositive, "QUIT")
+			s.session.Close()
+		}
+
+		h.num -= len(h.idle)
+		h.idle = nil
+	}
+}
+
+/***********************************************/
+
+// connect and log in to the server.
+func dialSession(pURL *url.URL, s *NetworkInfo) (ftpSession, TaskError) {
+	var (
+		c    ftpSession
+		err  error
+		addr = pURL.Host
+	)
+
+	if pURL.Scheme == "ftps" {
+		implicit := isImplicitFtps(s, pURL)
+
+		if pURL.Port() == "" && implicit {
+			addr = net.JoinHostPort(pURL.Hostname(), ftpsImplicitDefaultPort)
+		} else if pURL.Port() == "" {
+			addr = net.JoinHostPort(pURL.Hostname(), ftpsDefaultPort)
+		}
+
+		// each session has its own cache, so that the data connections resume the TLS session of the control connection by the ticket
+		var config *tls.Config
+
+		if config, err = s.tlsConfig(); err != nil {
+			return nil, taskError{err: err, flag: false}
+		}
+
+		config.ClientSessionCache = tls.NewLRUClientSessionCache(1)
+		c, err = NewFTPSConn(addr, config, implicit, sessionDialTimeout)
+
+		// the failure of verification is permanent
+		if isCertError(err) {
+			err = fmt.Errorf("failed to connect to the server, %s", err)
+			return nil, taskError{err: err, flag: false}
+		}
+	} else {
+		if pURL.Port() == "" {
+			addr = net.JoinHostPort(pURL.Hostname(), "21")
+		}
+
+		c, err = NewFTPConn(addr, sessionDialTimeout)
+	}
+
+	if err != nil {
+		err = fmt.Errorf("failed to connect to the server, %s", err)
+		return nil, taskError{err: err, flag: true}
+	}
+
+	if err = c.Login(s.UserName, s.Password); err != nil {
+		c.Close()
+		return nil, taskError{err: err, flag: false}
+	}
+
+	return c, nil
+}
+
+/***** METHOD **********************************/
+
+// get the sessions of the server.
+func (p *sessionPool) host(key string) *hostSessions {
+	h, ok := p.hosts[key]
+
+	if !ok {
+		h = &hostSessions{cond: sync.NewCond(&p.mutex)}
+		p.hosts[key] = h
+	}
+
+	return h
+}
+
+/***********************************************/
+
+// Get an authenticated session of the server. An idle session is reused if it is still alive, otherwise a new one
+// is dialed, if the number of sessions does not reach the maximum, or it waits for a session to be released.
+func (p *sessionPool) get(pURL *url.URL, src *NetworkInfo) (ftpSession, string, TaskError) {
+	p.once.Do(func() { go p.keepAlive() })
+	key := pURL.Scheme + "://" + src.UserName + "@" + pURL.Host
+
+	// the sessions with different TLS settings are not shared
+	if src.TLS != nil {
+		key += fmt.Sprintf("#%p", src.TLS)
+	}
+	maxNum := getLimiter(pURL.Hostname()).maxConn(p.maxNum)
+
+	p.mutex.Lock()
+	h := p.host(key)
+
+	for {
+		if n := len(h.idle); n > 0 {
+			s := h.idle[n-1].session
+			h.idle = h.idle[:n-1]
+			p.mutex.Unlock()
+
+			// the session may be closed by the server, then reconnect
+			if _, _, err := s.SendCommand(FTPCodePositive, "NOOP"); err == nil {
+				return s, key, nil
+			}
+
+			s.Close()
+			c, tErr := dialSession(pURL, src)
+
+			if tErr != nil {
+				p.release(key)
+				return nil, "", tErr
+			}
+
+			return c, key, nil
+		}
+
+		if h.num < maxNum {
+			h.num++
+			p.mutex.Unlock()
+			c, tErr := dialSession(pURL, src)
+
+			if tErr != nil {
+				p.release(key)
+				return nil, "", tErr
+			}
+
+			return c, key, nil
+		}
+
+		h.cond.Wait()
+	}
+}
+
+/***********************************************/
+
+// Return the session into the pool after use.
+func (p *sessionPool) put(key string, s ftpSession) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	h := p.host(key)
+	h.idle = append(h.idle, idleSession{session: s, since: time.Now(), ping: time.Now()})
+	h.cond.Signal()
+}
+
+/***********************************************/
+
+// Close the session whose state is unknown, e.g., after a failed transfer.
+func (p *sessionPool) drop(key string, s ftpSession) {
+	s.Close()
+	p.release(key)
+}
+
+/***********************************************/
+
+func (p *sessionPool) release(key string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	h := p.host(key)
+	h.num--
+	h.cond.Signal()
+}
+
+/***********************************************/
+
+// Send NOOP on idle sessions periodically to keep them alive, and close those idle for too long or dead.
+func (p *sessionPool) keepAlive() {
+	for range time.Tick(sessionCheckInterval) {
+		type pingSession struct {
+			key string
+			idleSession
+		}
+
+		var pings []pingSession
+		now := time.Now()
+
+		p.mutex.Lock()
+
+		for key, h := range p.hosts {
+			idle := h.idle[:0]
+
+			for _, s := range h.idle {
+				if now.Sub(s.ping) >= sessionKeepAlive {
+					pings = append(pings, pingSession{key: key, idleSession: s})
+				} else {
+					idle = append(idle, s)
+				}
+			}
+
+			h.idle = idle
+		}
+
+		p.mutex.Unlock()
+
+		for _, s := range pings {
+			if now.Sub(s.since) >= sessionMaxIdleTime {
+				s.session.SendCommand(FTPCodePositive, "QUIT")
+				p.drop(s.key, s.session)
+			} else if _, _, err := s.session.SendCommand(FTPCodePositive, "NOOP"); err != nil {
+				p.drop(s.key, s.session)
+			} else {
+				p.mutex.Lock()
+				h := p.host(s.key)
+				h.idle = append(h.idle, idleSession{session: s.session, since: s.since, ping: time.Now()})
+				h.cond.Signal()
+				p.mutex.Unlock()
+			}
+		}
+	}
+}
+
+/***********************************************/
@@ -0,0 +1,347 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+/***** CONSTANT ********************************/
+
+const sftpDefaultPort = "22"
+
+/***** VARIABLE ********************************/
+
+// the transfer is aborted if no data are read in time, while the shared client is kept for the others
+var errSFTPStalled = errors.New("no data received in time")
+
+/***** STRUCT **********************************/
+
+// SFTP client of one server and user, which is shared by goroutines since requests are multiplexed over the SSH connection.
+type sftpClient struct {
+	conn   *ssh.Client
+	client *sftp.Client
+}
+
+/***** METHOD **********************************/
+
+// Close the SSH connection first, otherwise closing the SFTP client may wait for the server forever.
+func (c *sftpClient) close() {
+	c.conn.Close()
+	c.client.Close()
+}
+
+/***** VARIABLE ********************************/
+
+var sftpClients = struct {
+	mutex   sync.Mutex
+	clients map[string]*sftpClient // key: username and address of the server
+}{clients: make(map[string]*sftpClient)}
+
+/***** FUNCTION ********************************/
+
+// Get the configuration of SSH, with the private key and/or the password, and the host key verified by the known_hosts file.
+func sftpConfig(s *NetworkInfo) (*ssh.ClientConfig, error) {
+	var auths []ssh.AuthMethod
+
+	if len(s.KeyFile) != 0 {
+		key, err := os.ReadFile(s.KeyFile)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the private key, %s", err)
+		}
+
+		var signer ssh.Signer
+
+		if len(s.KeyPassphrase) != 0 {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(key, []byte(s.KeyPassphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey(key)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the private key, %s", err)
+		}
+
+		auths = append(auths, ssh.PublicKeys(signer))
+	}
+
+	if len(s.Password) != 0 {
+		auths = append(auths, ssh.Password(s.Password))
+	}
+
+	knownHosts := s.KnownHosts
+
+	if len(knownHosts) == 0 {
+		home, err := os.UserHomeDir()
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the known_hosts file, %s", err)
+		}
+
+		knownHosts = filepath.Join(home, ".ssh", "known_hosts")
+	}
+
+	hostKeyCallback, err := knownhosts.New(knownHosts)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the known_hosts file, %s", err)
+	}
+
+	return &ssh.ClientConfig{
+		User:            s.UserName,
+		Auth:            auths,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         sessionDialTimeout,
+	}, nil
+}
+
+/***********************************************/
+
+// Get the shared client of the server in the url, which is connected if not yet.
+func getSFTPClient(s *NetworkInfo, pURL *url.URL) (*sftpClient, string, TaskError) {
+	port := pURL.Port()
+
+	if len(port) == 0 {
+		port = sftpDefaultPort
+	}
+
+	addr := net.JoinHostPort(pURL.Hostname(), port)
+	key := s.UserName + "@" + addr
+
+	sftpClients.mutex.Lock()
+	defer sftpClients.mutex.Unlock()
+
+	if c, ok := sftpClients.clients[key]; ok {
+		return c, key, nil
+	}
+
+	config, err := sftpConfig(s)
+
+	if err != nil {
+		return nil, key, taskError{err: err, flag: false}
+	}
+
+	conn, err := ssh.Dial("tcp", addr, config)
+
+	if err != nil {
+		// the failures of authentication and host key verification are permanent
+		var keyErr *knownhosts.KeyError
+		isAuth := errors.As(err, &keyErr) || strings.Contains(err.Error(), "unable to authenticate")
+		err = fmt.Errorf("failed to connect to %s, %s", addr, err)
+		return nil, key, taskError{err: err, flag: !isAuth}
+	}
+
+	client, err := sftp.NewClient(conn)
+
+	if err != nil {
+		conn.Close()
+		err = fmt.Errorf("failed to start SFTP on %s, %s", addr, err)
+		return nil, key, taskError{err: err, flag: true}
+	}
+
+	c := &sftpClient{conn: conn, client: client}
+	sftpClients.clients[key] = c
+	return c, key, nil
+}
+
+/***********************************************/
+
+// Close the client, e.g., after the connection is broken, and a new one would be connected for the next request.
+func dropSFTPClient(key string, c *sftpClient) {
+	sftpClients.mutex.Lock()
+
+	if sftpClients.clients[key] == c {
+		delete(sftpClients.clients, key)
+	}
+
+	sftpClients.mutex.Unlock()
+	c.close()
+}
+
+/***********************************************/
+
+// Close all SFTP clients, e.g., before exiting.
+func closeSFTPClients() {
+	sftpClients.mutex.Lock()
+	defer sftpClients.mutex.Unlock()
+
+	for key, c := range sftpClients.clients {
+		c.close()
+		delete(sftpClients.clients, key)
+	}
+}
+
+/***********************************************/
+
+// Convert the error of a request, the client is closed if the error is not reported by the server or a stall.
+func sftpError(key string, c *sftpClient, op string, err error) TaskError {
+	if errors.Is(err, os.ErrNotExist) {
+		return taskError{err: fmt.Errorf("failed to %s, %w, %s", op, ErrNotFound, err), flag: false}
+	}
+
+	var statusErr *sftp.StatusError
+
+	if errors.As(err, &statusErr) || errors.Is(err, os.ErrPermission) {
+		return taskError{err: fmt.Errorf("failed to %s, %s", op, err), flag: false}
+	}
+
+	// the connection may be slow for this file only, which is tried again later
+	if errors.Is(err, errSFTPStalled) {
+		return taskError{err: fmt.Errorf("failed to %s, %s", op, err), flag: true}
+	}
+
+	dropSFTPClient(key, c)
+	return taskError{err: fmt.Errorf("failed to %s, %s", op, err), flag: true}
+}
+
+/***********************************************/
+
+func SFTPList(s *NetworkInfo) ([]string, TaskError) {
+	pURL, err := url.Parse(s.Url)
+
+	if err != nil {
+		err = fmt.Errorf("falied to parse URL, %s", err)
+		return nil, taskError{err: err, flag: false}
+	}
+
+	limiter := getLimiter(pURL.Hostname())
+	defer limiter.acquire()()
+
+	c, key, tErr := getSFTPClient(s, pURL)
+
+	if tErr != nil {
+		return nil, tErr
+	}
+
+	infos, err := c.client.ReadDir(pURL.Path)
+
+	if err != nil {
+		return nil, sftpError(key, c, "list the directory", err)
+	}
+
+	var names []string
+
+	for _, info := range infos {
+		if info.Mode().IsRegular() {
+			names = append(names, path.Base(info.Name()))
+		}
+	}
+
+	return names, nil
+}
+
+/***********************************************/
+
+func SFTPStat(s *NetworkInfo) (FileInfo, TaskError) {
+	info := FileInfo{Size: -1}
+	pURL, err := url.Parse(s.Url)
+
+	if err != nil {
+		err = fmt.Errorf("falied to parse URL, %s", err)
+		return info, taskError{err: err, flag: false}
+	}
+
+	limiter := getLimiter(pURL.Hostname())
+	defer limiter.acquire()()
+
+	c, key, tErr := getSFTPClient(s, pURL)
+
+	if tErr != nil {
+		return info, tErr
+	}
+
+	fi, err := c.client.Stat(pURL.Path)
+
+	if err != nil {
+		return info, sftpError(key, c, "stat the file", err)
+	}
+
+	info.Size, info.ModTime = fi.Size(), fi.ModTime().UTC()
+	return info, nil
+}
+
+/***********************************************/
+
+// Download the file via SFTP.
+func SFTPDownload(f *NetworkTask) TaskError {
+	pURL, err := url.Parse(f.Source.Url)
+
+	if err != nil {
+		err = fmt.Errorf("falied to parse URL, %s", err)
+		return taskError{err: err, flag: false}
+	}
+
+	limiter := getLimiter(pURL.Hostname())
+	defer limiter.acquire()()
+
+	c, key, tErr := getSFTPClient(&f.Source, pURL)
+
+	if tErr != nil {
+		return tErr
+	}
+
+	src, err := c.client.Open(pURL.Path)
+
+	if err != nil {
+		return sftpError(key, c, "open the file", err)
+	}
+
+	fp, err := f.open(os.O_WRONLY | os.O_CREATE | os.O_TRUNC)
+
+	if err != nil {
+		src.Close()
+		return taskError{err: err, flag: false}
+	}
+
+	defer fp.Close()
+
+	// the file is read in another goroutine, since a pending read of the file cannot be interrupted by closing it.
+	// If no data are read in time, the pipe is closed, and the file is closed after the pending read returns.
+	pr, pw := io.Pipe()
+	timer := time.AfterFunc(time.Minute, func() { pw.CloseWithError(errSFTPStalled) })
+	defer timer.Stop()
+	defer pr.Close()
+
+	go func() {
+		_, err := io.Copy(pw, limiter.reader(src))
+		src.Close()
+		pw.CloseWithError(err)
+	}()
+
+	buf := make([]byte, 32*1024)
+
+	for {
+		timer.Reset(30 * time.Second)
+		nr, rErr := pr.Read(buf)
+
+		if nr > 0 {
+			nw, wErr := fp.Write(buf[:nr])
+			f.Size += int64(nw)
+
+			if wErr != nil {
+				return taskError{err: wErr, flag: false}
+			}
+		}
+
+		if rErr == io.EOF {
+			return nil
+		} else if rErr != nil {
+			return sftpError(key, c, "read the file", rErr)
+		}
+	}
+}
+
+/***********************************************/
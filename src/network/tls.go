@@ -0,0 +1,251 @@
+package network
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+/***** STRUCT **********************************/
+
+// TLS settings of a host for FTPS and HTTPS, which are given in its sources, the certificate of the server is verified by default.
+type TLSConfig struct {
+	Verify     *bool  `json:"verify"`      // whether to verify the certificate of the server, true by default
+	CAFile     string `json:"ca file"`     // PEM file of the CAs trusted besides the system ones, e.g., of the TLS inspection
+	CertFile   string `json:"cert file"`   // PEM file of the client certificate for mutual TLS, optional
+	KeyFile    string `json:"key file"`    // PEM file of the private key of the client certificate, the cert file by default
+	MinVersion string `json:"min version"` // minimum version, "1.0", "1.1", "1.2" (default) or "1.3"
+	ServerName string `json:"server name"` // name for SNI and verification instead of the host in the url, optional
+}
+
+/***** VARIABLE ********************************/
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+/***********************************************/
+
+// configs built from the settings, key: the settings of the hosts, nil for the default ones
+var tlsConfigs = struct {
+	mutex   sync.Mutex
+	configs map[*TLSConfig]*tls.Config
+}{configs: make(map[*TLSConfig]*tls.Config)}
+
+/***********************************************/
+
+// TLS settings of the hosts, which are given in the sources, key: host in lower case
+var tlsHosts = struct {
+	mutex     sync.Mutex
+	settings  map[string]*TLSConfig
+	transport *http.Transport // shared by the hosts with the settings, created when the first one is registered
+}{settings: make(map[string]*TLSConfig)}
+
+/***** METHOD **********************************/
+
+// Build the config of the settings, which may be nil for the default ones. The server name is left empty,
+// which must be set by the callers for each connection.
+func (t *TLSConfig) Build() (*tls.Config, error) {
+	tlsConfigs.mutex.Lock()
+	defer tlsConfigs.mutex.Unlock()
+
+	if config, ok := tlsConfigs.configs[t]; ok {
+		return config, nil
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if t != nil {
+		if t.Verify != nil && !*t.Verify {
+			config.InsecureSkipVerify = true
+		}
+
+		if len(t.MinVersion) != 0 {
+			version, ok := tlsVersions[t.MinVersion]
+
+			if !ok {
+				return nil, fmt.Errorf(`invalid "min version" "%s", which must be "1.0", "1.1", "1.2" or "1.3"`, t.MinVersion)
+			}
+
+			config.MinVersion = version
+		}
+
+		if len(t.CAFile) != 0 {
+			pem, err := os.ReadFile(t.CAFile)
+
+			if err != nil {
+				return nil, fmt.Errorf("failed to read the CA file, %s", err)
+			}
+
+			// the system CAs are still trusted
+			if config.RootCAs, err = x509.SystemCertPool(); err != nil {
+				config.RootCAs = x509.NewCertPool()
+			}
+
+			if !config.RootCAs.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf(`no certificates in the CA file "%s"`, t.CAFile)
+			}
+		}
+
+		if len(t.CertFile) != 0 {
+			keyFile := t.KeyFile
+
+			if len(keyFile) == 0 {
+				keyFile = t.CertFile
+			}
+
+			cert, err := tls.LoadX509KeyPair(t.CertFile, keyFile)
+
+			if err != nil {
+				return nil, fmt.Errorf("failed to load the client certificate, %s", err)
+			}
+
+			config.Certificates = []tls.Certificate{cert}
+		}
+	}
+
+	tlsConfigs.configs[t] = config
+	return config, nil
+}
+
+/***********************************************/
+
+// Register the settings of TLS for the host, which are used by all connections to it, including the ones redirected to it,
+// since the settings such as the CA and the client certificate belong to the server rather than to a source of a resource.
+// The sources of the same host must have the same settings.
+func RegisterTLSHost(host string, t *TLSConfig) error {
+	if _, err := t.Build(); err != nil {
+		return err
+	}
+
+	host = strings.ToLower(host)
+
+	tlsHosts.mutex.Lock()
+	defer tlsHosts.mutex.Unlock()
+
+	if old, ok := tlsHosts.settings[host]; ok {
+		if !reflect.DeepEqual(old, t) {
+			return fmt.Errorf(`conflicting settings of TLS for the host "%s", which must be the same in all sources of the host`, host)
+		}
+
+		return nil
+	}
+
+	tlsHosts.settings[host] = t
+	return nil
+}
+
+/***********************************************/
+
+// Get the config for the connections to the host, with the settings of the host, and the server name of them or the host.
+func getTLSConfig(host string) (*tls.Config, error) {
+	tlsHosts.mutex.Lock()
+	t := tlsHosts.settings[strings.ToLower(host)]
+	tlsHosts.mutex.Unlock()
+
+	base, err := t.Build()
+
+	if err != nil {
+		return nil, err
+	}
+
+	config := base.Clone()
+	config.ServerName = host
+
+	if t != nil && len(t.ServerName) != 0 {
+		config.ServerName = t.ServerName
+	}
+
+	return config, nil
+}
+
+/***********************************************/
+
+// Get the config for the connections to the host of the source, the settings of the source are registered for its host if any.
+func (s *NetworkInfo) tlsConfig() (*tls.Config, error) {
+	_, host := s.schemeHost()
+
+	if s.TLS != nil {
+		if err := RegisterTLSHost(host, s.TLS); err != nil {
+			return nil, err
+		}
+	}
+
+	return getTLSConfig(host)
+}
+
+/***********************************************/
+
+// Get the transport of HTTP, which is the default one if no host has settings of TLS,
+// or the one dialing each host, including the ones redirected to, with the settings of the host.
+func httpTransport(s *NetworkInfo) (*http.Transport, error) {
+	if s.TLS != nil {
+		_, host := s.schemeHost()
+
+		if err := RegisterTLSHost(host, s.TLS); err != nil {
+			return nil, err
+		}
+	}
+
+	tlsHosts.mutex.Lock()
+	defer tlsHosts.mutex.Unlock()
+
+	if len(tlsHosts.settings) == 0 {
+		return http.DefaultTransport.(*http.Transport), nil
+	}
+
+	if tlsHosts.transport == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+
+		transport.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			host, _, _ := net.SplitHostPort(addr)
+			config, err := getTLSConfig(host)
+
+			if err != nil {
+				return nil, err
+			}
+
+			dialer := tls.Dialer{Config: config}
+			return dialer.DialContext(ctx, network, addr)
+		}
+
+		tlsHosts.transport = transport
+	}
+
+	return tlsHosts.transport, nil
+}
+
+/***********************************************/
+
+// Get the client of HTTP with the TLS settings of the hosts, no timeout if timeout is zero.
+func httpClient(s *NetworkInfo, timeout time.Duration) (*http.Client, TaskError) {
+	transport, err := httpTransport(s)
+
+	if err != nil {
+		return nil, taskError{err: fmt.Errorf("invalid TLS settings, %s", err), flag: false}
+	}
+
+	return &http.Client{Transport: transport, Timeout: timeout}, nil
+}
+
+/***********************************************/
+
+// Check whether the error is the failure of verifying the certificate of the server, which is permanent.
+func isCertError(err error) bool {
+	var certErr *tls.CertificateVerificationError
+	return errors.As(err, &certErr)
+}
+
+/***********************************************/
@@ -0,0 +1,44 @@
+package network
+
+import "testing"
+
+func TestRegisterTLSHost(t *testing.T) {
+	verify := false
+
+	if err := RegisterTLSHost("Mirror.Example", &TLSConfig{ServerName: "archive.example"}); err != nil {
+		t.Fatalf("RegisterTLSHost: %s", err)
+	}
+
+	// the same settings in another source of the host
+	if err := RegisterTLSHost("mirror.example", &TLSConfig{ServerName: "archive.example"}); err != nil {
+		t.Errorf("RegisterTLSHost of the same settings: %s", err)
+	}
+
+	if err := RegisterTLSHost("mirror.example", &TLSConfig{Verify: &verify}); err == nil {
+		t.Errorf("RegisterTLSHost of conflicting settings: no error")
+	}
+
+	for _, tc := range []struct {
+		host       string
+		serverName string
+		insecure   bool
+	}{
+		{"mirror.example", "archive.example", false},
+		{"MIRROR.example", "archive.example", false},
+		{"other.example", "other.example", false},
+	} {
+		config, err := getTLSConfig(tc.host)
+
+		if err != nil {
+			t.Fatalf("getTLSConfig(%q): %s", tc.host, err)
+		}
+
+		if config.ServerName != tc.serverName || config.InsecureSkipVerify != tc.insecure {
+			t.Errorf("getTLSConfig(%q) = %q, %v, want %q, %v", tc.host, config.ServerName, config.InsecureSkipVerify, tc.serverName, tc.insecure)
+		}
+	}
+
+	if err := RegisterTLSHost("bad.example", &TLSConfig{MinVersion: "2.0"}); err == nil {
+		t.Errorf("RegisterTLSHost of invalid settings: no error")
+	}
+}
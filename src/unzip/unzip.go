@@ -8,21 +8,31 @@ package unzip
 
 import (
 	"compress/gzip"
+	"fmt"
 	"godog/unzip/lzw"
 	"io"
 	"os"
+	"strings"
 )
 
-func UnzipGZ(srcFile, desFile string) error {
-	srcFilePt, err := os.Open(srcFile)
+/***** FUNCTION ********************************/
 
-	if err != nil {
-		return err
+// Get a reader decompressing the data of r, according to the extension ".gz" or ".Z".
+func NewReader(r io.Reader, ext string) (io.Reader, error) {
+	if strings.EqualFold(ext, ".gz") {
+		return gzip.NewReader(r)
+	} else if strings.EqualFold(ext, ".Z") {
+		return lzw.NewReader(r)
+	} else {
+		return nil, fmt.Errorf(`unsupported compression format "%s"`, ext)
 	}
+}
 
-	defer srcFilePt.Close()
+/***********************************************/
 
-	srcReader, err := gzip.NewReader(srcFilePt)
+// Decompress the data in .gz format from r and write them into w.
+func UnzipGZStream(r io.Reader, w io.Writer) error {
+	srcReader, err := gzip.NewReader(r)
 
 	if err != nil {
 		return err
@@ -30,15 +40,26 @@ func UnzipGZ(srcFile, desFile string) error {
 
 	defer srcReader.Close()
 
-	desFilePt, err := os.Create(desFile)
+	_, err = io.Copy(w, srcReader)
 
-	if err != nil {
+	if err != nil && err != io.EOF {
 		return err
 	}
 
-	defer desFilePt.Close()
+	return nil
+}
+
+/***********************************************/
 
-	_, err = io.Copy(desFilePt, srcReader)
+// Decompress the data in .Z format from r and write them into w.
+func UnzipZStream(r io.Reader, w io.Writer) error {
+	srcReader, err := lzw.NewReader(r)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, srcReader)
 
 	if err != nil && err != io.EOF {
 		return err
@@ -47,7 +68,21 @@ func UnzipGZ(srcFile, desFile string) error {
 	return nil
 }
 
+/***********************************************/
+
+func UnzipGZ(srcFile, desFile string) error {
+	return unzipFile(srcFile, desFile, UnzipGZStream)
+}
+
+/***********************************************/
+
 func UnzipZ(srcFile, desFile string) error {
+	return unzipFile(srcFile, desFile, UnzipZStream)
+}
+
+/***********************************************/
+
+func unzipFile(srcFile, desFile string, fn func(io.Reader, io.Writer) error) error {
 	srcFilePt, err := os.Open(srcFile)
 
 	if err != nil {
@@ -56,12 +91,6 @@ func UnzipZ(srcFile, desFile string) error {
 
 	defer srcFilePt.Close()
 
-	srcReader, err := lzw.NewReader(srcFilePt)
-
-	if err != nil {
-		return err
-	}
-
 	desFilePt, err := os.Create(desFile)
 
 	if err != nil {
@@ -70,11 +99,5 @@ func UnzipZ(srcFile, desFile string) error {
 
 	defer desFilePt.Close()
 
-	_, err = io.Copy(desFilePt, srcReader)
-
-	if err != nil && err != io.EOF {
-		return err
-	}
-
-	return nil
+	return fn(srcFilePt, desFilePt)
 }
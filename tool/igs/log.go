@@ -0,0 +1,340 @@
+package igs
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	urlSiteLogIGS  = "https://files.igs.org/pub/station/log_9char/"
+	timeFmtSiteLog = "2006-01-02T15:04:05Z"
+)
+
+// Receiver installed at a site, valid from "date_installed" until "date_removed", which is empty if still installed.
+type ReceiverInfo struct {
+	Type      string `json:"receiver_type"`
+	System    string `json:"satellite_system"`
+	Serial    string `json:"serial_number"`
+	Firmware  string `json:"firmware"`
+	Installed string `json:"date_installed"`
+	Removed   string `json:"date_removed"`
+}
+
+// Antenna installed at a site, valid from "date_installed" until "date_removed", which is empty if still installed.
+type AntennaInfo struct {
+	Type      string     `json:"antenna_type"`
+	Radome    string     `json:"radome_type"`
+	Serial    string     `json:"antenna_serial_number"`
+	UNE       [3]float64 `json:"antenna_marker_une"`
+	Installed string     `json:"date_installed"`
+	Removed   string     `json:"date_removed"`
+}
+
+// History of a site parsed from its IGS site log.
+type SiteLog struct {
+	Name      string         `json:"name"`
+	File      string         `json:"file"`
+	Prepared  string         `json:"date_prepared"`
+	XYZ       [3]float64     `json:"xyz"`
+	Receivers []ReceiverInfo `json:"receivers"`
+	Antennas  []AntennaInfo  `json:"antennas"`
+}
+
+type SiteLogArray struct {
+	Array []SiteLog `json:"data"`
+}
+
+// Get the content from the url, which is tried at most 5 times.
+func httpGet(url string) ([]byte, error) {
+	return httpGetSince(url, time.Time{})
+}
+
+// Get the content from the url if it is modified since the time, otherwise nil is returned without error.
+// The condition is not sent if the time is zero.
+func httpGetSince(url string, since time.Time) ([]byte, error) {
+	var (
+		response *http.Response
+		err      error
+		client   = http.Client{Timeout: time.Minute}
+	)
+
+	request, _ := http.NewRequestWithContext(context.TODO(), http.MethodGet, url, nil)
+	request.Header.Add("User-Agent", httpUserAgent)
+
+	if !since.IsZero() {
+		request.Header.Set("If-Modified-Since", since.UTC().Format(http.TimeFormat))
+	}
+
+	for i := 0; i < 5; i++ {
+		if response, err = client.Do(request); err == nil && response.StatusCode == http.StatusNotModified {
+			response.Body.Close()
+			return nil, nil
+		} else if err == nil && response.StatusCode == http.StatusOK {
+			break
+		} else if err == nil {
+			response.Body.Close()
+			err = fmt.Errorf("unexpected status of %s, %s", url, response.Status)
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	return io.ReadAll(response.Body)
+}
+
+// Get the names of the latest site logs in the directory, e.g., "wuhn00chn_20230706.log", whose keys are the upper-case site names.
+func ListSiteLogs() (map[string]string, error) {
+	body, err := httpGet(urlSiteLogIGS)
+
+	if err != nil {
+		return nil, err
+	}
+
+	logs := make(map[string]string)
+	re := regexp.MustCompile(`href="([a-z0-9]{9})_(\d{8})\.log"`)
+
+	// the date is in the file name, and the latest one is kept
+	for _, matched := range re.FindAllStringSubmatch(string(body), -1) {
+		name := strings.ToUpper(matched[1])
+
+		if file := matched[1] + "_" + matched[2] + ".log"; file > logs[name] {
+			logs[name] = file
+		}
+	}
+
+	if len(logs) == 0 {
+		return nil, fmt.Errorf("no site log found in %s", urlSiteLogIGS)
+	}
+
+	return logs, nil
+}
+
+// Convert the date in the site log into the format of "2006-01-02T15:04:05Z", empty if not given.
+func parseLogDate(str string) string {
+	for _, layout := range []string{"2006-01-02T15:04Z", "2006-01-02T15:04:05Z", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.Parse(layout, str); err == nil {
+			return t.Format(timeFmtSiteLog)
+		}
+	}
+
+	return ""
+}
+
+// Parse the IGS site log, the receivers and antennas are sorted by the installation dates,
+// and the removal dates not given are filled with the next installation dates.
+func ParseSiteLog(r io.Reader) (*SiteLog, error) {
+	var (
+		site       SiteLog
+		section    int
+		reEntry    = regexp.MustCompile(`^(\d+)\.(\d+|x)?\s+([^:]*?)\s*:\s*(.*)$`)
+		reField    = regexp.MustCompile(`^\s+([^:]*?)\s*:\s*(.*)$`)
+		reSection  = regexp.MustCompile(`^(\d+)\.\s`)
+		rcv        *ReceiverInfo
+		ant        *AntennaInfo
+		isTemplate bool
+	)
+
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		var key, val string
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+
+		if matched := reEntry.FindStringSubmatch(line); matched != nil {
+			section, _ = strconv.Atoi(matched[1])
+			isTemplate = matched[2] == "x"
+			key, val = matched[3], matched[4]
+
+			// a new receiver or antenna begins with its sub-section
+			if !isTemplate && len(matched[2]) != 0 && section == 3 {
+				site.Receivers = append(site.Receivers, ReceiverInfo{})
+				rcv, ant = &site.Receivers[len(site.Receivers)-1], nil
+			} else if !isTemplate && len(matched[2]) != 0 && section == 4 {
+				site.Antennas = append(site.Antennas, AntennaInfo{})
+				rcv, ant = nil, &site.Antennas[len(site.Antennas)-1]
+			} else {
+				rcv, ant = nil, nil
+			}
+		} else if matched := reSection.FindStringSubmatch(line); matched != nil {
+			section, _ = strconv.Atoi(matched[1])
+			rcv, ant = nil, nil
+			continue
+		} else if matched := reField.FindStringSubmatch(line); matched != nil {
+			key, val = matched[1], matched[2]
+		} else {
+			continue
+		}
+
+		if isTemplate || len(key) == 0 {
+			continue
+		}
+
+		key = strings.ToLower(key)
+		num, _ := strconv.ParseFloat(val, 64)
+
+		switch {
+		case section == 0 && key == "date prepared":
+			site.Prepared = parseLogDate(val)
+		case section == 1 && (key == "nine character id" || key == "four character id") && len(site.Name) < 9:
+			site.Name = strings.ToUpper(val)
+		case section == 2 && strings.HasPrefix(key, "x coordinate"):
+			site.XYZ[0] = num
+		case section == 2 && strings.HasPrefix(key, "y coordinate"):
+			site.XYZ[1] = num
+		case section == 2 && strings.HasPrefix(key, "z coordinate"):
+			site.XYZ[2] = num
+		case rcv != nil:
+			switch key {
+			case "receiver type":
+				rcv.Type = val
+			case "satellite system":
+				rcv.System = val
+			case "serial number":
+				rcv.Serial = val
+			case "firmware version":
+				rcv.Firmware = val
+			case "date installed":
+				rcv.Installed = parseLogDate(val)
+			case "date removed":
+				rcv.Removed = parseLogDate(val)
+			}
+		case ant != nil:
+			switch {
+			case key == "antenna type": // the radome may be in columns 17-20
+				ant.Type = strings.TrimSpace(val[:min(16, len(val))])
+
+				if len(val) > 16 && len(ant.Radome) == 0 {
+					ant.Radome = strings.TrimSpace(val[16:])
+				}
+			case key == "serial number":
+				ant.Serial = val
+			case strings.HasPrefix(key, "marker->arp up"):
+				ant.UNE[0] = num
+			case strings.HasPrefix(key, "marker->arp north"):
+				ant.UNE[1] = num
+			case strings.HasPrefix(key, "marker->arp east"):
+				ant.UNE[2] = num
+			case key == "antenna radome type":
+				ant.Radome = val
+			case key == "date installed":
+				ant.Installed = parseLogDate(val)
+			case key == "date removed":
+				ant.Removed = parseLogDate(val)
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(site.Name) == 0 {
+		return nil, fmt.Errorf("invalid site log, no site name")
+	}
+
+	sort.SliceStable(site.Receivers, func(i, j int) bool { return site.Receivers[i].Installed < site.Receivers[j].Installed })
+	sort.SliceStable(site.Antennas, func(i, j int) bool { return site.Antennas[i].Installed < site.Antennas[j].Installed })
+
+	for i := 0; i+1 < len(site.Receivers); i++ {
+		if len(site.Receivers[i].Removed) == 0 {
+			site.Receivers[i].Removed = site.Receivers[i+1].Installed
+		}
+	}
+
+	for i := 0; i+1 < len(site.Antennas); i++ {
+		if len(site.Antennas[i].Removed) == 0 {
+			site.Antennas[i].Removed = site.Antennas[i+1].Installed
+		}
+	}
+
+	return &site, nil
+}
+
+// Download the site logs of the stations, in 4 or 9 characters, or of all stations if not given,
+// and write the parsed histories into the json file f. The site logs are also saved into dir if it is not empty.
+func GetSiteLogJson(f, dir string, stations []string) error {
+	var logArray SiteLogArray
+
+	logs, err := ListSiteLogs()
+
+	if err != nil {
+		return fmt.Errorf("failed to list the site logs, %s", err)
+	}
+
+	names := make([]string, 0, len(logs))
+
+	for name := range logs {
+		if len(stations) == 0 {
+			names = append(names, name)
+		}
+	}
+
+	for _, station := range stations {
+		found := false
+		station = strings.ToUpper(strings.TrimSpace(station))
+
+		for name := range logs {
+			if name == station || (len(station) == 4 && strings.HasPrefix(name, station)) {
+				names = append(names, name)
+				found = true
+			}
+		}
+
+		if !found {
+			fmt.Printf("no site log of station %s\n", station)
+		}
+	}
+
+	sort.Strings(names)
+
+	if len(dir) != 0 {
+		if err = os.MkdirAll(dir, 0775); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range names {
+		body, err := httpGet(urlSiteLogIGS + logs[name])
+
+		if err != nil {
+			fmt.Printf("failed to download the site log %s, %s\n", logs[name], err)
+			continue
+		}
+
+		if len(dir) != 0 {
+			if err = os.WriteFile(filepath.Join(dir, logs[name]), body, 0664); err != nil {
+				return err
+			}
+		}
+
+		site, err := ParseSiteLog(strings.NewReader(string(body)))
+
+		if err != nil {
+			fmt.Printf("failed to parse the site log %s, %s\n", logs[name], err)
+			continue
+		}
+
+		site.File = logs[name]
+		logArray.Array = append(logArray.Array, *site)
+	}
+
+	if len(logArray.Array) == 0 {
+		return fmt.Errorf("no site log got")
+	}
+
+	return WriteJson(f, &logArray)
+}
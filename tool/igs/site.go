@@ -1,19 +1,22 @@
 package igs
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
-	"net/http"
 	"os"
+	"sort"
+	"strings"
 	"time"
 )
 
 const (
-	httpUserAgent  = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_12_6) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/12.0.3 Safari/605.1.15"
-	urlSiteListIGS = "https://network.igs.org/api/public/stations/?include_former=on&format=json&length=2147483647"
+	httpUserAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_12_6) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/12.0.3 Safari/605.1.15"
+	siteListPage  = 1000 // number of sites in a page of the site list
 )
 
+// url of the IGS site list, which is replaced by the one of a local server in the tests
+var urlSiteListIGS = "https://network.igs.org/api/public/stations/?format=json"
+
 type SiteInfo struct {
 	Name      string     `json:"name"`
 	Status    int8       `json:"status"`
@@ -39,53 +42,251 @@ type SiteInfoArray struct {
 	Array  []SiteInfo `json:"data"`
 }
 
-func GetSiteInfoJson(f string) error {
-	var jSArray SiteInfoArray
-	fp, err := os.OpenFile(f, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0664)
+// Filters of the IGS site list, empty means no filter.
+type SiteFilter struct {
+	CurrentOnly bool     // only the current stations, without the former ones
+	Networks    []string // networks which the stations belong to, "IGS", "RT" or "MGEX", see siteNetworks
+}
+
+// Change of a field of a station between two site lists.
+type SiteChange struct {
+	Name  string `json:"name"`
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// Differences between the new site list and the old one.
+type SiteDiff struct {
+	Added   []string     `json:"added"`
+	Removed []string     `json:"removed"`
+	Changed []SiteChange `json:"changed"`
+}
+
+// Networks of IGS and the checks of their stations, since the site list has no field of the networks,
+// in which the real-time stations stream at least one system, and the MGEX stations track the systems other than GPS and GLONASS.
+var siteNetworks = map[string]func(site SiteInfo) bool{
+	"IGS": func(site SiteInfo) bool { return true },
+	"RT":  func(site SiteInfo) bool { return len(site.RtSystem) != 0 },
+	"MGEX": func(site SiteInfo) bool {
+		for _, sys := range site.System {
+			switch strings.ToUpper(sys) {
+			case "GAL", "BDS", "QZSS", "IRNSS":
+				return true
+			}
+		}
+
+		return false
+	},
+}
+
+// Check whether the networks of the filter are known.
+func (filter SiteFilter) Check() error {
+	for _, network := range filter.Networks {
+		if _, ok := siteNetworks[strings.ToUpper(strings.TrimSpace(network))]; !ok {
+			return fmt.Errorf(`unknown network "%s", which must be "IGS", "RT" or "MGEX"`, network)
+		}
+	}
+
+	return nil
+}
+
+// Check whether the station is kept by the filter, i.e., it belongs to any of the networks.
+func (filter SiteFilter) keep(site SiteInfo) bool {
+	if len(filter.Networks) == 0 {
+		return true
+	}
+
+	for _, network := range filter.Networks {
+		if check, ok := siteNetworks[strings.ToUpper(strings.TrimSpace(network))]; ok && check(site) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Write v into the json file f atomically, i.e., it is written into a temporary file and renamed.
+func WriteJson(f string, v any) error {
+	fp, err := os.OpenFile(f+".tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0664)
 
 	if err != nil {
 		return err
 	}
 
-	defer fp.Close()
+	ecr := json.NewEncoder(fp)
+	ecr.SetIndent("", "    ")
+	err = ecr.Encode(v)
+
+	if cErr := fp.Close(); err == nil {
+		err = cErr
+	}
+
+	if err == nil {
+		err = os.Rename(f+".tmp", f)
+	}
 
-	client := http.Client{}
-	ctx, cancel := context.WithCancel(context.TODO())
-	timer := time.AfterFunc(time.Minute, func() { cancel() })
-	request, _ := http.NewRequest(http.MethodGet, urlSiteListIGS, nil)
-	request.Header.Add("User-Agent", httpUserAgent)
-	request = request.WithContext(ctx)
+	if err != nil {
+		os.Remove(f + ".tmp")
+	}
 
-	var response *http.Response
+	return err
+}
 
-	for i := 0; i < 5; i++ {
-		timer.Reset(time.Minute)
-		response, err = client.Do(request)
+// Read the site list in the json file.
+func readSiteInfoJson(f string) (*SiteInfoArray, error) {
+	var jSArray SiteInfoArray
 
-		if err == nil && response.StatusCode == http.StatusOK {
-			break
+	data, err := os.ReadFile(f)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(data, &jSArray); err != nil {
+		return nil, err
+	}
+
+	return &jSArray, nil
+}
+
+// Get the differences between the site lists, in which the receivers and antennas are compared.
+func diffSiteInfo(oldArray, newArray *SiteInfoArray) *SiteDiff {
+	diff := SiteDiff{Added: []string{}, Removed: []string{}, Changed: []SiteChange{}}
+	oldSites := make(map[string]SiteInfo, len(oldArray.Array))
+
+	for _, site := range oldArray.Array {
+		oldSites[site.Name] = site
+	}
+
+	for _, site := range newArray.Array {
+		old, ok := oldSites[site.Name]
+
+		if !ok {
+			diff.Added = append(diff.Added, site.Name)
+			continue
+		}
+
+		delete(oldSites, site.Name)
+
+		for _, field := range [][3]string{
+			{"receiver_type", old.RcvType, site.RcvType},
+			{"serial_number", old.RcvSerial, site.RcvSerial},
+			{"firmware", old.RcvFirm, site.RcvFirm},
+			{"antenna_type", old.AntType, site.AntType},
+			{"antenna_serial_number", old.AntSerial, site.AntSerial},
+			{"antenna_marker_une", fmt.Sprint(old.AntUNE), fmt.Sprint(site.AntUNE)},
+		} {
+			if field[1] != field[2] {
+				diff.Changed = append(diff.Changed, SiteChange{Name: site.Name, Field: field[0], Old: field[1], New: field[2]})
+			}
 		}
 	}
 
-	if err != nil {
-		return err
+	for name := range oldSites {
+		diff.Removed = append(diff.Removed, name)
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+
+	return &diff
+}
+
+// Download the IGS site list with the filter.
+func GetSiteInfo(filter SiteFilter) (*SiteInfoArray, error) {
+	return GetSiteInfoSince(filter, time.Time{})
+}
+
+// Download the IGS site list with the filter page by page, and nil is returned without error if the list is not modified
+// since the time, which is not checked if zero. The servers not supporting the condition always send the list.
+func GetSiteInfoSince(filter SiteFilter, since time.Time) (*SiteInfoArray, error) {
+	var jSArray SiteInfoArray
+
+	url := urlSiteListIGS
+
+	if !filter.CurrentOnly {
+		url += "&include_former=on"
 	}
 
-	defer response.Body.Close()
+	names := make(map[string]bool)
 
-	dcr := json.NewDecoder(response.Body)
+	for start := 0; ; start += siteListPage {
+		var page SiteInfoArray
 
-	for dcr.More() {
-		err = dcr.Decode(&jSArray)
+		// only the first page is conditional
+		body, err := httpGetSince(fmt.Sprintf("%s&start=%d&length=%d", url, start, siteListPage), since)
 
 		if err != nil {
-			return fmt.Errorf("fialed to parse the response body, %s", err)
+			return nil, err
+		} else if body == nil {
+			return nil, nil
+		}
+
+		since = time.Time{}
+
+		if err = json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse the response body, %s", err)
+		}
+
+		jSArray.NumAll = page.NumAll
+
+		// the sites may be shifted between the pages if the list is changed meanwhile
+		for _, site := range page.Array {
+			if !names[site.Name] && filter.keep(site) {
+				jSArray.Array = append(jSArray.Array, site)
+			}
+
+			names[site.Name] = true
+		}
+
+		if len(page.Array) < siteListPage || int64(start+siteListPage) >= page.Num {
+			break
 		}
 	}
 
-	ecr := json.NewEncoder(fp)
-	ecr.SetIndent("", "    ")
-	ecr.Encode(&jSArray)
+	jSArray.Num = int64(len(jSArray.Array))
 
-	return nil
+	return &jSArray, nil
+}
+
+// Write the site list into the json file f atomically, i.e., the old file is kept if failed.
+// The differences against the old file are returned, which are empty if there is no old file.
+func UpdateSiteInfoJson(f string, jSArray *SiteInfoArray) (*SiteDiff, error) {
+	if len(jSArray.Array) == 0 {
+		return nil, fmt.Errorf("no site got")
+	}
+
+	// compare with the old file before it is replaced
+	diff := &SiteDiff{}
+
+	if oldArray, err := readSiteInfoJson(f); err == nil {
+		diff = diffSiteInfo(oldArray, jSArray)
+	}
+
+	if err := WriteJson(f, jSArray); err != nil {
+		return nil, err
+	}
+
+	return diff, nil
+}
+
+// Download the IGS site list with the filter, and write it into the json file f, see UpdateSiteInfoJson().
+// The file is kept with no differences if the list is not modified since the file was written.
+func GetSiteInfoJson(f string, filter SiteFilter) (*SiteDiff, error) {
+	var since time.Time
+
+	if info, err := os.Stat(f); err == nil {
+		since = info.ModTime()
+	}
+
+	jSArray, err := GetSiteInfoSince(filter, since)
+
+	if err != nil {
+		return nil, err
+	} else if jSArray == nil {
+		return &SiteDiff{Added: []string{}, Removed: []string{}, Changed: []SiteChange{}}, nil
+	}
+
+	return UpdateSiteInfoJson(f, jSArray)
 }
@@ -0,0 +1,140 @@
+package igs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Read the records of the IGS site list saved from the API.
+func readTestSites(t *testing.T) []SiteInfo {
+	jSArray, err := readSiteInfoJson("../../input/SiteInfoIGS.json")
+
+	if err != nil {
+		t.Fatalf("failed to read the site list, %s", err)
+	}
+
+	return jSArray.Array
+}
+
+func TestSiteFilterNetworks(t *testing.T) {
+	sites := readTestSites(t)
+	get := func(name string) SiteInfo {
+		for _, site := range sites {
+			if site.Name == name {
+				return site
+			}
+		}
+
+		t.Fatalf("no site %s in the site list", name)
+		return SiteInfo{}
+	}
+
+	// ABMF00GLP streams in real time and tracks Galileo and BeiDou, ADE100AUS only tracks GPS and GLONASS
+	for _, tc := range []struct {
+		networks []string
+		site     string
+		want     bool
+	}{
+		{nil, "ADE100AUS", true},
+		{[]string{"IGS"}, "ADE100AUS", true},
+		{[]string{"MGEX"}, "ABMF00GLP", true},
+		{[]string{"mgex"}, "ADE100AUS", false},
+		{[]string{"RT"}, "ABMF00GLP", true},
+		{[]string{"RT"}, "ADE100AUS", false},
+		{[]string{"RT", " MGEX"}, "ABMF00GLP", true},
+	} {
+		filter := SiteFilter{Networks: tc.networks}
+
+		if err := filter.Check(); err != nil {
+			t.Fatalf("%v: %s", tc.networks, err)
+		}
+
+		if got := filter.keep(get(tc.site)); got != tc.want {
+			t.Errorf("%v: keep(%s) = %v, want %v", tc.networks, tc.site, got, tc.want)
+		}
+	}
+
+	// some but not all sites are kept
+	for _, network := range []string{"RT", "MGEX"} {
+		num := 0
+
+		for _, site := range sites {
+			if (SiteFilter{Networks: []string{network}}).keep(site) {
+				num++
+			}
+		}
+
+		if num == 0 || num == len(sites) {
+			t.Errorf("%s: %d of %d sites kept", network, num, len(sites))
+		}
+	}
+
+	if err := (SiteFilter{Networks: []string{"EUREF"}}).Check(); err == nil {
+		t.Errorf("Check of an unknown network: no error")
+	}
+}
+
+func TestGetSiteInfoSince(t *testing.T) {
+	var (
+		record   = readTestSites(t)[0]
+		sites    = make([]SiteInfo, 2500)
+		modTime  = time.Date(2025, 12, 5, 14, 4, 58, 0, time.UTC)
+		requests []string
+	)
+
+	for i := range sites {
+		sites[i] = record
+		sites[i].Name = fmt.Sprintf("S%03d00XXX", i)
+	}
+
+	// the site list is paged by "start" and "length"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Query().Get("start"))
+
+		if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !modTime.After(since) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+		length, _ := strconv.Atoi(r.URL.Query().Get("length"))
+		page := SiteInfoArray{Num: int64(len(sites)), NumAll: int64(len(sites)), Array: sites[min(start, len(sites)):min(start+length, len(sites))]}
+
+		w.Header().Set("Last-Modified", modTime.Format(http.TimeFormat))
+		json.NewEncoder(w).Encode(&page)
+	}))
+	defer server.Close()
+
+	defer func(url string) { urlSiteListIGS = url }(urlSiteListIGS)
+	urlSiteListIGS = server.URL + "/?format=json"
+
+	jSArray, err := GetSiteInfoSince(SiteFilter{}, modTime.Add(-time.Hour))
+
+	if err != nil {
+		t.Fatalf("GetSiteInfoSince: %s", err)
+	}
+
+	if jSArray == nil || len(jSArray.Array) != len(sites) || jSArray.Num != int64(len(sites)) {
+		t.Fatalf("got %v sites, want %d", jSArray, len(sites))
+	}
+
+	if fmt.Sprint(requests) != "[0 1000 2000]" {
+		t.Errorf("requests of the pages %v, want [0 1000 2000]", requests)
+	}
+
+	// not modified since the last refresh
+	requests = nil
+
+	if jSArray, err = GetSiteInfoSince(SiteFilter{}, modTime); err != nil || jSArray != nil {
+		t.Errorf("GetSiteInfoSince of the list not modified: %v, %v, want nil", jSArray, err)
+	}
+
+	if len(requests) != 1 {
+		t.Errorf("%d requests of the list not modified, want 1", len(requests))
+	}
+}
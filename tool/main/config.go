@@ -2,4 +2,12 @@ package main
 
 type Config struct {
 	SiteFileIGS string
+	SiteFile    string // path of the json file of the sites from the provider
+	Provider    string // e.g., "igs", "sinex:PATH" or "csv:PATH"
+	SiteDiff    string // path of the json file of the differences, optional
+	CurrentOnly bool
+	Networks    string // networks separated by commas, optional
+	SiteLogIGS  string // path of the json file of the site logs
+	SiteLogDir  string // directory to save the site logs, optional
+	Stations    string // stations separated by commas, optional
 }
@@ -12,7 +12,9 @@ package main
 import (
 	"flag"
 	"fmt"
+	"strings"
 	"todog/igs"
+	"todog/site"
 )
 
 func main() {
@@ -21,15 +23,64 @@ func main() {
 	var err error
 
 	flag.StringVar(&cfg.SiteFileIGS, "IGS-sites-info", "", "the path of the json file to store the information of IGS sites")
+	flag.StringVar(&cfg.SiteFile, "sites-info", "", "the path of the json file to store the information of sites from the provider")
+	flag.StringVar(&cfg.Provider, "provider", "igs", `the provider of sites information, "igs", "sinex:PATH" or "csv:PATH"`)
+	flag.StringVar(&cfg.SiteDiff, "sites-diff", "", "the path of the json file to store the differences against the old information of sites, optional")
+	flag.BoolVar(&cfg.CurrentOnly, "current-only", false, "only the current IGS sites, without the former ones, only for the provider \"igs\"")
+	flag.StringVar(&cfg.Networks, "networks", "", `the networks of IGS sites separated by commas, "IGS", "RT" (real-time) or "MGEX" (multi-GNSS), all networks if empty, only for the provider "igs"`)
+	flag.StringVar(&cfg.SiteLogIGS, "IGS-site-logs", "", "the path of the json file to store the histories parsed from IGS site logs")
+	flag.StringVar(&cfg.SiteLogDir, "IGS-site-logs-dir", "", "the directory to store the downloaded IGS site logs, optional")
+	flag.StringVar(&cfg.Stations, "stations", "", `the stations of the site logs separated by commas, e.g., "WUHN,ABMF00GLP", all stations if empty`)
 	flag.Parse()
 
+	// "-IGS-sites-info" is the same as "-sites-info" with the IGS provider
 	if cfg.SiteFileIGS != "" {
-		err = igs.GetSiteInfoJson(cfg.SiteFileIGS)
+		cfg.SiteFile, cfg.Provider = cfg.SiteFileIGS, "igs"
+	}
+
+	if cfg.SiteFile != "" {
+		filter := igs.SiteFilter{CurrentOnly: cfg.CurrentOnly}
+
+		if cfg.Networks != "" {
+			filter.Networks = strings.Split(cfg.Networks, ",")
+		}
+
+		provider, err := site.NewProvider(cfg.Provider, filter)
+
+		if err != nil {
+			panic(err)
+		}
+
+		diff, err := site.GetSiteInfoJson(cfg.SiteFile, provider)
+
+		if err != nil {
+			panic(fmt.Sprintf("failed to get the json file of sites information, %s", err))
+		} else {
+			fmt.Println("finished to get the json file of sites information from", provider.Name())
+		}
+
+		fmt.Printf("%d sites added, %d removed, %d fields changed\n", len(diff.Added), len(diff.Removed), len(diff.Changed))
+
+		if cfg.SiteDiff != "" {
+			if err = igs.WriteJson(cfg.SiteDiff, diff); err != nil {
+				panic(fmt.Sprintf("failed to write the differences of sites information, %s", err))
+			}
+		}
+	}
+
+	if cfg.SiteLogIGS != "" {
+		var stations []string
+
+		if cfg.Stations != "" {
+			stations = strings.Split(cfg.Stations, ",")
+		}
+
+		err = igs.GetSiteLogJson(cfg.SiteLogIGS, cfg.SiteLogDir, stations)
 
 		if err != nil {
-			panic(fmt.Sprintf("failed to get the json file of IGS sites information, %s", err))
+			panic(fmt.Sprintf("failed to get the json file of IGS site logs, %s", err))
 		} else {
-			fmt.Println("finished to get the json file of IGS sites information")
+			fmt.Println("finished to get the json file of IGS site logs")
 		}
 	}
 }
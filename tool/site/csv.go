@@ -0,0 +1,130 @@
+package site
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"todog/igs"
+)
+
+// Provider of the sites in a CSV file with a header line, e.g., exported from the station list of a regional network.
+// The columns are recognized by the names in the header, which are case-insensitive and the others are ignored:
+//
+//	name, status, x, y, z, lat, lon, height, satellite_system, receiver_type, serial_number, firmware,
+//	antenna_type, antenna_serial_number, up, north, east
+//
+// The satellite systems are separated by "+" or spaces, e.g., "GPS+GLO+GAL", and the latitudes and longitudes
+// are computed from the coordinates if not given.
+type CSVProvider struct {
+	Path string
+}
+
+func (p CSVProvider) Name() string {
+	return "CSV " + p.Path
+}
+
+func (p CSVProvider) Sites() (*igs.SiteInfoArray, error) {
+	var jSArray igs.SiteInfoArray
+
+	fp, err := os.Open(p.Path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer fp.Close()
+
+	reader := csv.NewReader(fp)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	lines, err := reader.ReadAll()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("no site in %s", p.Path)
+	}
+
+	cols := make(map[string]int)
+
+	for i, key := range lines[0] {
+		cols[strings.ToLower(strings.TrimSpace(key))] = i
+	}
+
+	if _, ok := cols["name"]; !ok {
+		return nil, fmt.Errorf(`no column "name" in %s`, p.Path)
+	}
+
+	for n, line := range lines[1:] {
+		var (
+			site   igs.SiteInfo
+			hasLLH bool
+			errs   []string
+		)
+
+		get := func(key string) string {
+			if i, ok := cols[key]; ok && i < len(line) {
+				return strings.TrimSpace(line[i])
+			}
+
+			return ""
+		}
+
+		num := func(key string, v *float64) bool {
+			if str := get(key); len(str) != 0 {
+				var err error
+
+				if *v, err = strconv.ParseFloat(str, 64); err != nil {
+					errs = append(errs, key)
+				}
+
+				return err == nil
+			}
+
+			return false
+		}
+
+		if site.Name = strings.ToUpper(get("name")); len(site.Name) == 0 {
+			return nil, fmt.Errorf("no name of the site in line %d of %s", n+2, p.Path)
+		}
+
+		if str := get("status"); len(str) != 0 {
+			status, err := strconv.ParseInt(str, 10, 8)
+
+			if err != nil {
+				errs = append(errs, "status")
+			}
+
+			site.Status = int8(status)
+		}
+
+		num("x", &site.XYZ[0])
+		num("y", &site.XYZ[1])
+		num("z", &site.XYZ[2])
+		hasLLH = num("lat", &site.LLH[0]) && num("lon", &site.LLH[1])
+		num("height", &site.LLH[2])
+		num("up", &site.AntUNE[0])
+		num("north", &site.AntUNE[1])
+		num("east", &site.AntUNE[2])
+
+		if len(errs) != 0 {
+			return nil, fmt.Errorf("invalid %s in line %d of %s", strings.Join(errs, ", "), n+2, p.Path)
+		}
+
+		if !hasLLH && site.XYZ != [3]float64{} {
+			site.LLH = xyz2llh(site.XYZ)
+		}
+
+		site.System = strings.FieldsFunc(get("satellite_system"), func(r rune) bool { return r == '+' || r == ' ' })
+		site.RcvType, site.RcvSerial, site.RcvFirm = get("receiver_type"), get("serial_number"), get("firmware")
+		site.AntType, site.AntSerial = get("antenna_type"), get("antenna_serial_number")
+
+		jSArray.Array = append(jSArray.Array, site)
+	}
+
+	return &jSArray, nil
+}
@@ -0,0 +1,102 @@
+package site
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"todog/igs"
+)
+
+// Source of a site catalogue, which produces the site list in the same shape as the IGS one,
+// so that it could be used as the information file of targets in GoDOG.
+type Provider interface {
+	Name() string
+	Sites() (*igs.SiteInfoArray, error)
+}
+
+// Provider which could skip the download if the sites are not modified since the time, in which nil is returned without error.
+type IncrementalProvider interface {
+	Provider
+	SitesSince(since time.Time) (*igs.SiteInfoArray, error)
+}
+
+// Provider of the IGS site list from network.igs.org.
+type IGSProvider struct {
+	Filter igs.SiteFilter
+}
+
+func (p IGSProvider) Name() string {
+	return "IGS"
+}
+
+func (p IGSProvider) Sites() (*igs.SiteInfoArray, error) {
+	return igs.GetSiteInfo(p.Filter)
+}
+
+func (p IGSProvider) SitesSince(since time.Time) (*igs.SiteInfoArray, error) {
+	return igs.GetSiteInfoSince(p.Filter, since)
+}
+
+// Get the provider by the specification in the form of "kind:path", e.g., "igs", "sinex:/data/igs25P2390.snx"
+// or "csv:/data/cors.csv". The filter is only supported by the IGS provider, since the SINEX and CSV files
+// have neither the former stations nor the networks.
+func NewProvider(spec string, filter igs.SiteFilter) (Provider, error) {
+	kind, path, _ := strings.Cut(spec, ":")
+
+	var p Provider
+
+	switch strings.ToLower(kind) {
+	case "igs":
+		if err := filter.Check(); err != nil {
+			return nil, err
+		}
+
+		return IGSProvider{Filter: filter}, nil
+	case "sinex", "snx":
+		p = SINEXProvider{Path: path}
+	case "csv":
+		p = CSVProvider{Path: path}
+	default:
+		return nil, fmt.Errorf(`unsupported provider "%s", which must be "igs", "sinex:PATH" or "csv:PATH"`, kind)
+	}
+
+	if filter.CurrentOnly || len(filter.Networks) != 0 {
+		return nil, fmt.Errorf(`the filters of current sites and networks are only supported by the provider "igs", not "%s"`, kind)
+	}
+
+	return p, nil
+}
+
+// Get the site list from the provider, and write it into the json file f, see igs.UpdateSiteInfoJson().
+// The file is kept with no differences if the provider is incremental and the sites are not modified since the file was written.
+func GetSiteInfoJson(f string, p Provider) (*igs.SiteDiff, error) {
+	var (
+		jSArray *igs.SiteInfoArray
+		err     error
+	)
+
+	if ip, ok := p.(IncrementalProvider); ok {
+		var since time.Time
+
+		if info, err := os.Stat(f); err == nil {
+			since = info.ModTime()
+		}
+
+		if jSArray, err = ip.SitesSince(since); err == nil && jSArray == nil {
+			return &igs.SiteDiff{Added: []string{}, Removed: []string{}, Changed: []igs.SiteChange{}}, nil
+		}
+	} else {
+		jSArray, err = p.Sites()
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the sites from %s, %s", p.Name(), err)
+	}
+
+	if jSArray.Num = int64(len(jSArray.Array)); jSArray.NumAll == 0 {
+		jSArray.NumAll = jSArray.Num
+	}
+
+	return igs.UpdateSiteInfoJson(f, jSArray)
+}
@@ -0,0 +1,252 @@
+package site
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"todog/igs"
+)
+
+const (
+	wgs84A = 6378137.0
+	wgs84F = 1 / 298.257223563
+)
+
+// Provider of the sites in a SINEX file, e.g., the IGS weekly solution, which works offline.
+// The sites are from the SITE/ID block, the receivers, antennas and eccentricities valid at the latest epoch
+// are from the SITE/RECEIVER, SITE/ANTENNA and SITE/ECCENTRICITY blocks, and the coordinates are from the
+// SOLUTION/ESTIMATE block. The names are the 4-character codes, and the status and satellite systems are unknown.
+type SINEXProvider struct {
+	Path string // path of the SINEX file, which may be compressed by gzip
+}
+
+// Record valid in an interval, in the SITE/RECEIVER, SITE/ANTENNA or SITE/ECCENTRICITY block.
+type sinexRecord struct {
+	start  time.Time
+	fields []string
+}
+
+func (p SINEXProvider) Name() string {
+	return "SINEX " + p.Path
+}
+
+func (p SINEXProvider) Sites() (*igs.SiteInfoArray, error) {
+	fp, err := os.Open(p.Path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer fp.Close()
+
+	var r io.Reader = fp
+
+	if strings.HasSuffix(strings.ToLower(p.Path), ".gz") {
+		if r, err = gzip.NewReader(fp); err != nil {
+			return nil, err
+		}
+	}
+
+	return ParseSINEX(r)
+}
+
+// Convert the time in SINEX, i.e., "YY:DOY:SECOD", where "00:000:00000" means unknown.
+func parseSINEXTime(str string) time.Time {
+	var yy, doy, sod int
+
+	if n, _ := fmt.Sscanf(strings.TrimSpace(str), "%d:%d:%d", &yy, &doy, &sod); n != 3 || doy == 0 {
+		return time.Time{}
+	}
+
+	if yy < 50 {
+		yy += 2000
+	} else if yy < 100 {
+		yy += 1900
+	}
+
+	return time.Date(yy, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, doy-1).Add(time.Duration(sod) * time.Second)
+}
+
+// Get the field of the line in columns [st, ed), which are 0-based.
+func column(line string, st, ed int) string {
+	if st >= len(line) {
+		return ""
+	}
+
+	return strings.TrimSpace(line[st:min(ed, len(line))])
+}
+
+// Convert the ECEF coordinates into the geodetic latitude and longitude in degrees, and the height in meters.
+func xyz2llh(xyz [3]float64) [3]float64 {
+	p := math.Hypot(xyz[0], xyz[1])
+	e2 := wgs84F * (2 - wgs84F)
+	lat := math.Atan2(xyz[2], p*(1-e2))
+	n := wgs84A
+
+	for i := 0; i < 5; i++ {
+		sinLat := math.Sin(lat)
+		n = wgs84A / math.Sqrt(1-e2*sinLat*sinLat)
+		lat = math.Atan2(xyz[2]+e2*n*sinLat, p)
+	}
+
+	h := p/math.Cos(lat) - n
+
+	if math.Abs(lat) > math.Pi/4 {
+		h = xyz[2]/math.Sin(lat) - n*(1-e2)
+	}
+
+	return [3]float64{lat * 180 / math.Pi, math.Atan2(xyz[1], xyz[0]) * 180 / math.Pi, h}
+}
+
+// Parse the approximate longitude or latitude in SITE/ID, i.e., "DDD MM SS.S".
+func parseDMS(str string) float64 {
+	var d, m, s float64
+
+	fmt.Sscanf(str, "%f %f %f", &d, &m, &s)
+
+	if strings.HasPrefix(strings.TrimSpace(str), "-") {
+		return d - m/60 - s/3600
+	}
+
+	return d + m/60 + s/3600
+}
+
+// Parse the sites in SINEX.
+func ParseSINEX(r io.Reader) (*igs.SiteInfoArray, error) {
+	var (
+		block   string
+		codes   []string
+		sites   = make(map[string]*igs.SiteInfo)
+		records = make(map[string]map[string][]sinexRecord) // key: block, then site code
+	)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 1024*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \r")
+
+		if len(line) == 0 || line[0] == '*' {
+			continue
+		} else if line[0] == '+' {
+			block = strings.TrimSpace(line[1:])
+			continue
+		} else if line[0] == '-' {
+			block = ""
+			continue
+		} else if line[0] != ' ' {
+			continue
+		}
+
+		code := strings.ToUpper(column(line, 1, 5))
+
+		switch block {
+		case "SITE/ID":
+			if _, ok := sites[code]; ok || len(line) < 75 {
+				continue
+			}
+
+			site := &igs.SiteInfo{Name: code}
+			lon := parseDMS(column(line, 44, 55))
+
+			if lon > 180 {
+				lon -= 360
+			}
+
+			site.LLH = [3]float64{parseDMS(column(line, 56, 67)), lon, 0}
+			site.LLH[2], _ = strconv.ParseFloat(column(line, 68, 75), 64)
+			sites[code] = site
+			codes = append(codes, code)
+		case "SITE/RECEIVER", "SITE/ANTENNA", "SITE/ECCENTRICITY":
+			if records[block] == nil {
+				records[block] = make(map[string][]sinexRecord)
+			}
+
+			var fields []string
+
+			switch block {
+			case "SITE/RECEIVER": // type, serial number and firmware
+				fields = []string{column(line, 42, 62), column(line, 63, 68), column(line, 69, 80)}
+			case "SITE/ANTENNA": // type with the radome, and serial number
+				fields = []string{column(line, 42, 62), column(line, 63, 68)}
+			case "SITE/ECCENTRICITY": // reference system, and up, north and east
+				fields = []string{column(line, 42, 45), column(line, 46, 54), column(line, 55, 63), column(line, 64, 72)}
+			}
+
+			records[block][code] = append(records[block][code], sinexRecord{start: parseSINEXTime(column(line, 16, 28)), fields: fields})
+		case "SOLUTION/ESTIMATE":
+			fields := strings.Fields(line)
+
+			// the last estimates are kept if there are several solutions
+			if len(fields) < 9 || len(fields[1]) != 4 || !strings.HasPrefix(fields[1], "STA") {
+				continue
+			}
+
+			site, ok := sites[strings.ToUpper(fields[2])]
+			value, err := strconv.ParseFloat(fields[8], 64)
+
+			if !ok || err != nil {
+				continue
+			}
+
+			if i := strings.IndexByte("XYZ", fields[1][3]); i >= 0 {
+				site.XYZ[i] = value
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(codes) == 0 {
+		return nil, fmt.Errorf("no site in SITE/ID")
+	}
+
+	// the records valid at the latest epoch
+	latest := func(block, code string) []string {
+		recs := records[block][code]
+
+		if len(recs) == 0 {
+			return nil
+		}
+
+		sort.SliceStable(recs, func(i, j int) bool { return recs[i].start.Before(recs[j].start) })
+		return recs[len(recs)-1].fields
+	}
+
+	var jSArray igs.SiteInfoArray
+
+	for _, code := range codes {
+		site := sites[code]
+
+		if fields := latest("SITE/RECEIVER", code); fields != nil {
+			site.RcvType, site.RcvSerial, site.RcvFirm = fields[0], fields[1], fields[2]
+		}
+
+		if fields := latest("SITE/ANTENNA", code); fields != nil {
+			site.AntType, site.AntSerial = strings.TrimSpace(fields[0][:min(16, len(fields[0]))]), fields[1]
+		}
+
+		if fields := latest("SITE/ECCENTRICITY", code); fields != nil && fields[0] == "UNE" {
+			for i := range site.AntUNE {
+				site.AntUNE[i], _ = strconv.ParseFloat(fields[i+1], 64)
+			}
+		}
+
+		if site.XYZ != [3]float64{} {
+			site.LLH = xyz2llh(site.XYZ)
+		}
+
+		jSArray.Array = append(jSArray.Array, *site)
+	}
+
+	return &jSArray, nil
+}